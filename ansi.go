@@ -0,0 +1,163 @@
+package markdown
+
+import (
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// ANSI escape sequences used by ToANSI.
+const (
+    ansiReset     = "\x1b[0m"
+    ansiBold      = "\x1b[1m"
+    ansiItalic    = "\x1b[3m"
+    ansiUnderline = "\x1b[4m"
+    ansiStrike    = "\x1b[9m"
+    ansiCode      = "\x1b[36m"
+)
+
+// ansiColors maps CSS color names to the nearest of the eight basic ANSI
+// foreground colors.
+var ansiColors = map[string]int{
+    "black":   30,
+    "red":     31,
+    "maroon":  31,
+    "crimson": 31,
+    "green":   32,
+    "lime":    32,
+    "olive":   33,
+    "yellow":  33,
+    "orange":  33,
+    "gold":    33,
+    "blue":    34,
+    "navy":    34,
+    "magenta": 35,
+    "fuchsia": 35,
+    "purple":  35,
+    "violet":  35,
+    "pink":    35,
+    "cyan":    36,
+    "aqua":    36,
+    "teal":    36,
+    "white":   37,
+    "silver":  37,
+    "gray":    37,
+    "grey":    37,
+}
+
+// ansiRGB holds the reference colors of the basic ANSI foreground codes, used
+// to find the nearest code for a hex color.
+var ansiRGB = [8][3]int{
+    {0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+    {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+}
+
+var (
+    ansiHeadingPattern   = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)(?:[ \t]+\{[^{}]*\})?[ \t]*$`)
+    ansiColorPattern     = regexp.MustCompile(`<span style="color:([^"]*)">(.*?)</span>`)
+    ansiBoldPattern      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+    ansiItalicPattern    = regexp.MustCompile(`(^|[^\w*])(?:\*([^*]+)\*|_([^_]+)_)($|[^\w*])`)
+    ansiStrikePattern    = regexp.MustCompile(`~~([^~]+)~~`)
+    ansiUnderlinePattern = regexp.MustCompile(`<u>(.*?)</u>`)
+)
+
+// ToANSI renders the Markdown content for display in a terminal. Headings,
+// bold, italic, strikethrough, underlined, and colored text as well as inline
+// code are styled with ANSI escape codes, each styled span followed by a reset.
+// Colors produced by ColorText are mapped to the nearest basic ANSI color.
+// Fenced code blocks are printed without their fences.
+//
+// Returns:
+// - string: The content with ANSI escape codes
+func (md *Markdown) ToANSI() string {
+    var out strings.Builder
+    var fences fenceState
+    for _, line := range strings.SplitAfter(md.GetContent(), "\n") {
+        text := strings.TrimSuffix(line, "\n")
+        newline := line[len(text):]
+        switch fences.scan(line) {
+        case openingFence, closingFence:
+            continue // Fences are not printed
+        case codeLine:
+            out.WriteString(ansiCode + text + ansiReset + newline)
+            continue
+        }
+        if m := ansiHeadingPattern.FindStringSubmatch(text); m != nil {
+            text = ansiBold + ansiUnderline + ansiInline(m[2]) + ansiReset
+        } else {
+            text = ansiInline(text)
+        }
+        out.WriteString(text + newline)
+    }
+    return out.String()
+}
+
+// ansiInline styles the inline Markdown of a single line.
+func ansiInline(line string) string {
+    line = mapOutsideCodeSpans(line, func(prose string) string {
+        prose = ansiColorPattern.ReplaceAllStringFunc(prose, func(span string) string {
+            m := ansiColorPattern.FindStringSubmatch(span)
+            code, ok := ansiColor(m[1])
+            if !ok {
+                return m[2]
+            }
+            return "\x1b[" + strconv.Itoa(code) + "m" + m[2] + ansiReset
+        })
+        prose = ansiBoldPattern.ReplaceAllString(prose, ansiBold+"$1$2"+ansiReset)
+        prose = ansiItalicPattern.ReplaceAllString(prose, "$1"+ansiItalic+"$2$3"+ansiReset+"$4")
+        prose = ansiStrikePattern.ReplaceAllString(prose, ansiStrike+"$1"+ansiReset)
+        return ansiUnderlinePattern.ReplaceAllString(prose, ansiUnderline+"$1"+ansiReset)
+    })
+    return ansiCodeSpans(line)
+}
+
+// ansiCodeSpans replaces inline code spans in line with their styled content.
+func ansiCodeSpans(line string) string {
+    var out strings.Builder
+    for i := 0; i < len(line); {
+        if line[i] != '`' {
+            out.WriteByte(line[i])
+            i++
+            continue
+        }
+        n := 0
+        for i+n < len(line) && line[i+n] == '`' {
+            n++
+        }
+        end := closingBackticks(line, i+n, n)
+        if end < 0 {
+            out.WriteString(line[i : i+n]) // Unmatched backticks are literal text
+            i += n
+            continue
+        }
+        code := line[i+n : end]
+        if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+            code = code[1 : len(code)-1]
+        }
+        out.WriteString(ansiCode + code + ansiReset)
+        i = end + n
+    }
+    return out.String()
+}
+
+// ansiColor returns the ANSI foreground code nearest to a CSS color name, a hex
+// color, or an rgb(r, g, b) color. Names without a code of their own are
+// resolved to their CSS value first.
+func ansiColor(color string) (int, bool) {
+    color = strings.ToLower(strings.TrimSpace(color))
+    if code, ok := ansiColors[color]; ok {
+        return code, true
+    }
+    r, g, b, ok := colorRGB(color)
+    if !ok {
+        return 0, false
+    }
+    best, bestDist := 0, -1
+    for i, ref := range ansiRGB {
+        dr, dg, db := r-ref[0], g-ref[1], b-ref[2]
+        if dist := dr*dr + dg*dg + db*db; bestDist < 0 || dist < bestDist {
+            best, bestDist = i, dist
+        }
+    }
+    return 30 + best, true
+}
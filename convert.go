@@ -0,0 +1,127 @@
+package markdown
+
+import (
+    "fmt"
+    "html"
+    "regexp"
+    "strings"
+)
+
+var (
+    taskItemPattern       = regexp.MustCompile(`^(\s*[-*+]\s+)\[[ xX]\]\s+`)
+    strikethroughPattern  = regexp.MustCompile(`~~([^~]+)~~`)
+    alertMarkerPattern    = regexp.MustCompile(`^>\s*\[!([A-Z]+)\]\s*$`)
+    tableSeparatorPattern = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+    footnotePattern       = regexp.MustCompile(`\[\^[^\]]+\]`)
+    inlineHTMLPattern     = regexp.MustCompile(`<[a-zA-Z/!][^<>]*>|&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+)
+
+// ConvertFlavor produces a new document with the content of md converted to
+// the target flavor. When converting to StandardMarkdown, constructs specific to
+// GitHub-flavored Markdown are downgraded: task lists become bullet lists,
+// strikethrough becomes <del>, pipe tables become HTML tables, and alerts become
+// plain blockquotes. Inline HTML in table cells, such as <br> line breaks, is
+// kept. Code blocks are left untouched. The other flavors support
+// all of these constructs, so their content is copied unchanged.
+//
+// Parameters:
+// - target: The flavor to convert to (StandardMarkdown, GitHubMarkdown, JupyterMarkdown)
+//
+// Returns:
+// - *Markdown: A new document with the converted content
+// - error: An error if the flavor is unknown or the content uses GFM footnote references such as [^1], which standard Markdown cannot represent
+func (md *Markdown) ConvertFlavor(target int) (*Markdown, error) {
+    if target != StandardMarkdown && target != GitHubMarkdown && target != JupyterMarkdown {
+        return nil, fmt.Errorf("markdown: unknown flavor %d", target)
+    }
+    content := md.GetContent()
+    if target == StandardMarkdown {
+        var err error
+        if content, err = downgradeGFM(content); err != nil {
+            return nil, err
+        }
+    }
+    converted := md.sub()
+    converted.flavor = target
+    converted.content.WriteString(content)
+    return converted, nil
+}
+
+// downgradeGFM rewrites the GitHub-specific constructs in text as standard
+// Markdown or HTML.
+func downgradeGFM(text string) (string, error) {
+    var out strings.Builder
+    lines := strings.Split(text, "\n")
+    var fences fenceState
+    for i := 0; i < len(lines); i++ {
+        line := lines[i]
+        if fences.scan(line) != proseLine {
+            out.WriteString(line + "\n") // Code blocks are left untouched
+            continue
+        }
+        trimmed := strings.TrimLeft(line, " ")
+        if strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && tableSeparatorPattern.MatchString(lines[i+1]) {
+            headers := tableRowCells(line)
+            var rows [][]TableCell
+            for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimLeft(lines[i], " "), "|"); i++ {
+                rows = append(rows, tableRowCells(lines[i]))
+            }
+            i-- // Reprocess the line after the table
+            out.WriteString(strings.TrimRight(htmlTable(headers, rows, nil, escapeCellHTML), "\n") + "\n")
+            continue
+        }
+        if m := alertMarkerPattern.FindStringSubmatch(line); m != nil {
+            out.WriteString("> **" + m[1][:1] + strings.ToLower(m[1][1:]) + "**\n")
+            continue
+        }
+        var err error
+        line = mapOutsideCodeSpans(line, func(prose string) string {
+            if footnotePattern.MatchString(prose) {
+                err = fmt.Errorf("markdown: footnotes cannot be represented in standard Markdown")
+            }
+            prose = taskItemPattern.ReplaceAllString(prose, "$1")
+            return strikethroughPattern.ReplaceAllString(prose, "<del>$1</del>")
+        })
+        if err != nil {
+            return "", err
+        }
+        out.WriteString(line + "\n")
+    }
+    return strings.TrimSuffix(out.String(), "\n"), nil
+}
+
+// escapeCellHTML escapes the text of a pipe table cell for an HTML table.
+// Inline HTML such as the <br> written by EscapeTableCell, and character
+// references, are already HTML and kept as they are.
+func escapeCellHTML(text string) string {
+    var b strings.Builder
+    start := 0
+    for _, loc := range inlineHTMLPattern.FindAllStringIndex(text, -1) {
+        b.WriteString(html.EscapeString(text[start:loc[0]]))
+        b.WriteString(text[loc[0]:loc[1]])
+        start = loc[1]
+    }
+    b.WriteString(html.EscapeString(text[start:]))
+    return b.String()
+}
+
+// tableRowCells splits a pipe table row into its cells.
+func tableRowCells(line string) []TableCell {
+    line = strings.TrimSpace(line)
+    line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+    var cells []TableCell
+    var cell strings.Builder
+    for i := 0; i < len(line); i++ {
+        switch {
+        case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+            cell.WriteByte('|')
+            i++
+        case line[i] == '|':
+            cells = append(cells, TableCell{Text: strings.TrimSpace(cell.String())})
+            cell.Reset()
+        default:
+            cell.WriteByte(line[i])
+        }
+    }
+    return append(cells, TableCell{Text: strings.TrimSpace(cell.String())})
+}
@@ -0,0 +1,140 @@
+package markdown
+
+import (
+    "fmt"
+    "strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffLine is a line of a diff: kind is ' ' for unchanged, '-' for removed, or
+// '+' for added lines.
+type diffLine struct {
+    kind byte
+    text string
+}
+
+// Diff compares the contents of two documents line by line and returns the
+// differences in unified diff format, with three lines of context around each
+// change, e.g. to assert in tests exactly how generated output changed.
+//
+// Parameters:
+// - a: The original document
+// - b: The changed document
+//
+// Returns:
+// - string: The unified diff, or "" if the contents are equal
+func Diff(a, b *Markdown) string {
+    before, after := a.GetContent(), b.GetContent()
+    if before == after {
+        return ""
+    }
+    lines := diffLines(splitLines(before), splitLines(after))
+    var out strings.Builder
+    out.WriteString("--- a\n+++ b\n")
+    for start := 0; start < len(lines); {
+        if lines[start].kind == ' ' {
+            start++
+            continue
+        }
+        // Extend the hunk until diffContext*2 unchanged lines separate changes
+        end, unchanged := start, 0
+        for i := start; i < len(lines) && unchanged <= 2*diffContext; i++ {
+            if lines[i].kind == ' ' {
+                unchanged++
+            } else {
+                end, unchanged = i+1, 0
+            }
+        }
+        from := start - diffContext
+        if from < 0 {
+            from = 0
+        }
+        to := end + diffContext
+        if to > len(lines) {
+            to = len(lines)
+        }
+        writeHunk(&out, lines, from, to)
+        start = to
+    }
+    return out.String()
+}
+
+// splitLines splits text into lines without their line endings.
+func splitLines(text string) []string {
+    if text == "" {
+        return nil
+    }
+    return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a line diff of a and b from their longest common
+// subsequence.
+func diffLines(a, b []string) []diffLine {
+    // lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+    lcs := make([][]int, len(a)+1)
+    for i := range lcs {
+        lcs[i] = make([]int, len(b)+1)
+    }
+    for i := len(a) - 1; i >= 0; i-- {
+        for j := len(b) - 1; j >= 0; j-- {
+            if a[i] == b[j] {
+                lcs[i][j] = lcs[i+1][j+1] + 1
+            } else if lcs[i+1][j] >= lcs[i][j+1] {
+                lcs[i][j] = lcs[i+1][j]
+            } else {
+                lcs[i][j] = lcs[i][j+1]
+            }
+        }
+    }
+    var lines []diffLine
+    i, j := 0, 0
+    for i < len(a) || j < len(b) {
+        switch {
+        case i < len(a) && j < len(b) && a[i] == b[j]:
+            lines = append(lines, diffLine{' ', a[i]})
+            i++
+            j++
+        case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+            lines = append(lines, diffLine{'-', a[i]})
+            i++
+        default:
+            lines = append(lines, diffLine{'+', b[j]})
+            j++
+        }
+    }
+    return lines
+}
+
+// writeHunk writes lines[from:to] as a hunk with its range header.
+func writeHunk(out *strings.Builder, lines []diffLine, from, to int) {
+    aStart, bStart := 1, 1
+    for _, line := range lines[:from] {
+        if line.kind != '+' {
+            aStart++
+        }
+        if line.kind != '-' {
+            bStart++
+        }
+    }
+    aCount, bCount := 0, 0
+    for _, line := range lines[from:to] {
+        if line.kind != '+' {
+            aCount++
+        }
+        if line.kind != '-' {
+            bCount++
+        }
+    }
+    if aCount == 0 {
+        aStart-- // An empty range starts at the line before it
+    }
+    if bCount == 0 {
+        bStart--
+    }
+    out.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount))
+    for _, line := range lines[from:to] {
+        out.WriteString(string(line.kind) + line.text + "\n")
+    }
+}
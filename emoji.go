@@ -0,0 +1,541 @@
+package markdown
+
+// emojiShortcodes maps common GitHub emoji shortcodes (without colons) to
+// their Unicode representation.
+var emojiShortcodes = map[string]string{
+    "+1":                              "👍",
+    "-1":                              "👎",
+    "100":                             "💯",
+    "1st_place_medal":                 "🥇",
+    "2nd_place_medal":                 "🥈",
+    "3rd_place_medal":                 "🥉",
+    "airplane":                        "✈️",
+    "alarm_clock":                     "⏰",
+    "alembic":                         "⚗️",
+    "alien":                           "👽",
+    "ambulance":                       "🚑",
+    "anchor":                          "⚓",
+    "anger":                           "💢",
+    "angry":                           "😠",
+    "anguished":                       "😧",
+    "ant":                             "🐜",
+    "apple":                           "🍎",
+    "arrow_down":                      "⬇️",
+    "arrow_left":                      "⬅️",
+    "arrow_right":                     "➡️",
+    "arrow_up":                        "⬆️",
+    "arrows_clockwise":                "🔃",
+    "arrows_counterclockwise":         "🔄",
+    "art":                             "🎨",
+    "astonished":                      "😲",
+    "avocado":                         "🥑",
+    "baby":                            "👶",
+    "back":                            "🔙",
+    "bacon":                           "🥓",
+    "balloon":                         "🎈",
+    "ballot_box_with_check":           "☑️",
+    "banana":                          "🍌",
+    "bangbang":                        "‼️",
+    "bar_chart":                       "📊",
+    "baseball":                        "⚾",
+    "basketball":                      "🏀",
+    "bat":                             "🦇",
+    "battery":                         "🔋",
+    "bear":                            "🐻",
+    "bee":                             "🐝",
+    "beer":                            "🍺",
+    "beers":                           "🍻",
+    "beetle":                          "🐞",
+    "beginner":                        "🔰",
+    "bell":                            "🔔",
+    "bike":                            "🚲",
+    "bird":                            "🐦",
+    "birthday":                        "🎂",
+    "black_circle":                    "⚫",
+    "black_flag":                      "🏴",
+    "black_heart":                     "🖤",
+    "black_large_square":              "⬛",
+    "blossom":                         "🌼",
+    "blue_book":                       "📘",
+    "blue_heart":                      "💙",
+    "blue_square":                     "🟦",
+    "blush":                           "😊",
+    "boat":                            "⛵",
+    "bomb":                            "💣",
+    "book":                            "📖",
+    "bookmark":                        "🔖",
+    "bookmark_tabs":                   "📑",
+    "books":                           "📚",
+    "boom":                            "💥",
+    "bouquet":                         "💐",
+    "bow":                             "🙇",
+    "boy":                             "👦",
+    "brain":                           "🧠",
+    "bread":                           "🍞",
+    "briefcase":                       "💼",
+    "broccoli":                        "🥦",
+    "broken_heart":                    "💔",
+    "bug":                             "🐛",
+    "bulb":                            "💡",
+    "burrito":                         "🌯",
+    "bus":                             "🚌",
+    "butterfly":                       "🦋",
+    "cactus":                          "🌵",
+    "cake":                            "🍰",
+    "calendar":                        "📆",
+    "call_me_hand":                    "🤙",
+    "camel":                           "🐪",
+    "camera":                          "📷",
+    "candle":                          "🕯️",
+    "candy":                           "🍬",
+    "car":                             "🚗",
+    "card_index":                      "📇",
+    "carrot":                          "🥕",
+    "cat":                             "🐱",
+    "cd":                              "💿",
+    "champagne":                       "🍾",
+    "chart_with_downwards_trend":      "📉",
+    "chart_with_upwards_trend":        "📈",
+    "checkered_flag":                  "🏁",
+    "cheese":                          "🧀",
+    "cherries":                        "🍒",
+    "cherry_blossom":                  "🌸",
+    "chicken":                         "🐔",
+    "chocolate_bar":                   "🍫",
+    "clap":                            "👏",
+    "clipboard":                       "📋",
+    "closed_book":                     "📕",
+    "cloud":                           "☁️",
+    "cloud_with_rain":                 "🌧️",
+    "clown_face":                      "🤡",
+    "cocktail":                        "🍸",
+    "coconut":                         "🥥",
+    "coffee":                          "☕",
+    "cold_sweat":                      "😰",
+    "collision":                       "💥",
+    "computer":                        "💻",
+    "computer_mouse":                  "🖱️",
+    "confetti_ball":                   "🎊",
+    "confounded":                      "😖",
+    "confused":                        "😕",
+    "construction":                    "🚧",
+    "construction_worker":             "👷",
+    "cookie":                          "🍪",
+    "cool":                            "🆒",
+    "copyright":                       "©️",
+    "corn":                            "🌽",
+    "couple":                          "👫",
+    "cow":                             "🐮",
+    "cowboy_hat_face":                 "🤠",
+    "crab":                            "🦀",
+    "credit_card":                     "💳",
+    "crescent_moon":                   "🌙",
+    "crocodile":                       "🐊",
+    "croissant":                       "🥐",
+    "crossed_fingers":                 "🤞",
+    "crown":                           "👑",
+    "cry":                             "😢",
+    "cupid":                           "💘",
+    "dancer":                          "💃",
+    "dart":                            "🎯",
+    "dash":                            "💨",
+    "date":                            "📅",
+    "deciduous_tree":                  "🌳",
+    "desktop_computer":                "🖥️",
+    "detective":                       "🕵️",
+    "disappointed":                    "😞",
+    "dizzy":                           "💫",
+    "dizzy_face":                      "😵",
+    "dna":                             "🧬",
+    "dog":                             "🐶",
+    "dollar":                          "💵",
+    "dolphin":                         "🐬",
+    "doughnut":                        "🍩",
+    "dragon":                          "🐉",
+    "dress":                           "👗",
+    "drooling_face":                   "🤤",
+    "droplet":                         "💧",
+    "dvd":                             "📀",
+    "eagle":                           "🦅",
+    "earth_africa":                    "🌍",
+    "earth_americas":                  "🌎",
+    "earth_asia":                      "🌏",
+    "egg":                             "🥚",
+    "eggplant":                        "🍆",
+    "electric_plug":                   "🔌",
+    "elephant":                        "🐘",
+    "email":                           "✉️",
+    "end":                             "🔚",
+    "envelope":                        "✉️",
+    "euro":                            "💶",
+    "evergreen_tree":                  "🌲",
+    "exclamation":                     "❗",
+    "exploding_head":                  "🤯",
+    "expressionless":                  "😑",
+    "eye":                             "👁",
+    "eyeglasses":                      "👓",
+    "eyes":                            "👀",
+    "face_with_thermometer":           "🤒",
+    "facepalm":                        "🤦",
+    "facepunch":                       "👊",
+    "factory":                         "🏭",
+    "fallen_leaf":                     "🍂",
+    "family":                          "👪",
+    "fearful":                         "😨",
+    "file_folder":                     "📁",
+    "fire":                            "🔥",
+    "fire_engine":                     "🚒",
+    "fish":                            "🐟",
+    "fist":                            "✊",
+    "flashlight":                      "🔦",
+    "floppy_disk":                     "💾",
+    "flushed":                         "😳",
+    "football":                        "🏈",
+    "four_leaf_clover":                "🍀",
+    "fox_face":                        "🦊",
+    "free":                            "🆓",
+    "fries":                           "🍟",
+    "frog":                            "🐸",
+    "frowning":                        "😦",
+    "fuel_pump":                       "⛽",
+    "full_moon":                       "🌕",
+    "game_die":                        "🎲",
+    "gear":                            "⚙️",
+    "gem":                             "💎",
+    "ghost":                           "👻",
+    "gift":                            "🎁",
+    "gift_heart":                      "💝",
+    "giraffe":                         "🦒",
+    "girl":                            "👧",
+    "globe_with_meridians":            "🌐",
+    "gorilla":                         "🦍",
+    "grapes":                          "🍇",
+    "green_apple":                     "🍏",
+    "green_book":                      "📗",
+    "green_circle":                    "🟢",
+    "green_heart":                     "💚",
+    "green_square":                    "🟩",
+    "grey_exclamation":                "❕",
+    "grey_question":                   "❔",
+    "grimacing":                       "😬",
+    "grin":                            "😁",
+    "grinning":                        "😀",
+    "guitar":                          "🎸",
+    "hamburger":                       "🍔",
+    "hammer":                          "🔨",
+    "hammer_and_wrench":               "🛠️",
+    "hamster":                         "🐹",
+    "hand":                            "✋",
+    "handbag":                         "👜",
+    "handshake":                       "🤝",
+    "hankey":                          "💩",
+    "headphones":                      "🎧",
+    "hear_no_evil":                    "🙉",
+    "heart":                           "❤️",
+    "heart_eyes":                      "😍",
+    "heart_eyes_cat":                  "😻",
+    "heartbeat":                       "💓",
+    "heartpulse":                      "💗",
+    "heavy_check_mark":                "✔️",
+    "heavy_division_sign":             "➗",
+    "heavy_exclamation_mark":          "❗",
+    "heavy_minus_sign":                "➖",
+    "heavy_multiplication_x":          "✖️",
+    "heavy_plus_sign":                 "➕",
+    "helicopter":                      "🚁",
+    "herb":                            "🌿",
+    "hibiscus":                        "🌺",
+    "horse":                           "🐴",
+    "hospital":                        "🏥",
+    "hot_pepper":                      "🌶️",
+    "hotdog":                          "🌭",
+    "hourglass":                       "⌛",
+    "hourglass_flowing_sand":          "⏳",
+    "house":                           "🏠",
+    "hugs":                            "🤗",
+    "hushed":                          "😯",
+    "icecream":                        "🍦",
+    "imp":                             "👿",
+    "inbox_tray":                      "📥",
+    "incoming_envelope":               "📨",
+    "infinity":                        "♾️",
+    "information_source":              "ℹ️",
+    "innocent":                        "😇",
+    "interrobang":                     "⁉️",
+    "iphone":                          "📱",
+    "jeans":                           "👖",
+    "jigsaw":                          "🧩",
+    "joy":                             "😂",
+    "key":                             "🔑",
+    "keyboard":                        "⌨️",
+    "kiss":                            "💋",
+    "kissing":                         "😗",
+    "kissing_heart":                   "😘",
+    "kiwi_fruit":                      "🥝",
+    "koala":                           "🐨",
+    "label":                           "🏷️",
+    "large_blue_circle":               "🔵",
+    "large_blue_diamond":              "🔷",
+    "large_orange_diamond":            "🔶",
+    "laughing":                        "😆",
+    "ledger":                          "📒",
+    "lemon":                           "🍋",
+    "link":                            "🔗",
+    "lion":                            "🦁",
+    "lips":                            "👄",
+    "lipstick":                        "💄",
+    "lock":                            "🔒",
+    "lollipop":                        "🍭",
+    "loudspeaker":                     "📢",
+    "lying_face":                      "🤥",
+    "mag":                             "🔍",
+    "mag_right":                       "🔎",
+    "magnet":                          "🧲",
+    "mailbox":                         "📫",
+    "man":                             "👨",
+    "maple_leaf":                      "🍁",
+    "mask":                            "😷",
+    "medal_sports":                    "🏅",
+    "mega":                            "📣",
+    "memo":                            "📝",
+    "metal":                           "🤘",
+    "microphone":                      "🎤",
+    "microscope":                      "🔬",
+    "money_mouth_face":                "🤑",
+    "moneybag":                        "💰",
+    "monkey_face":                     "🐵",
+    "mortar_board":                    "🎓",
+    "mouse":                           "🐭",
+    "movie_camera":                    "🎥",
+    "muscle":                          "💪",
+    "mushroom":                        "🍄",
+    "musical_note":                    "🎵",
+    "nauseated_face":                  "🤢",
+    "necktie":                         "👔",
+    "nerd_face":                       "🤓",
+    "neutral_face":                    "😐",
+    "new":                             "🆕",
+    "new_moon":                        "🌑",
+    "newspaper":                       "📰",
+    "ninja":                           "🥷",
+    "no_bell":                         "🔕",
+    "no_entry":                        "⛔",
+    "no_entry_sign":                   "🚫",
+    "no_mouth":                        "😶",
+    "notebook":                        "📓",
+    "notes":                           "🎶",
+    "nut_and_bolt":                    "🔩",
+    "o":                               "⭕",
+    "ocean":                           "🌊",
+    "octopus":                         "🐙",
+    "office":                          "🏢",
+    "ok":                              "🆗",
+    "ok_hand":                         "👌",
+    "old_key":                         "🗝️",
+    "older_man":                       "👴",
+    "older_woman":                     "👵",
+    "open_book":                       "📖",
+    "open_file_folder":                "📂",
+    "open_hands":                      "👐",
+    "open_mouth":                      "😮",
+    "orange_book":                     "📙",
+    "orange_circle":                   "🟠",
+    "orange_heart":                    "🧡",
+    "outbox_tray":                     "📤",
+    "owl":                             "🦉",
+    "package":                         "📦",
+    "page_facing_up":                  "📄",
+    "page_with_curl":                  "📃",
+    "palm_tree":                       "🌴",
+    "pancakes":                        "🥞",
+    "panda_face":                      "🐼",
+    "paperclip":                       "📎",
+    "partly_sunny":                    "⛅",
+    "partying_face":                   "🥳",
+    "paw_prints":                      "🐾",
+    "peach":                           "🍑",
+    "pear":                            "🍐",
+    "pen":                             "🖊️",
+    "pencil":                          "📝",
+    "pencil2":                         "✏️",
+    "penguin":                         "🐧",
+    "pensive":                         "😔",
+    "persevere":                       "😣",
+    "pig":                             "🐷",
+    "pill":                            "💊",
+    "pinching_hand":                   "🤏",
+    "pineapple":                       "🍍",
+    "pizza":                           "🍕",
+    "point_down":                      "👇",
+    "point_left":                      "👈",
+    "point_right":                     "👉",
+    "point_up":                        "☝️",
+    "point_up_2":                      "👆",
+    "poop":                            "💩",
+    "potato":                          "🥔",
+    "pray":                            "🙏",
+    "printer":                         "🖨️",
+    "punch":                           "👊",
+    "purple_circle":                   "🟣",
+    "purple_heart":                    "💜",
+    "pushpin":                         "📌",
+    "question":                        "❓",
+    "rabbit":                          "🐰",
+    "radio":                           "📻",
+    "rage":                            "😡",
+    "rainbow":                         "🌈",
+    "raised_hand":                     "✋",
+    "raised_hands":                    "🙌",
+    "raising_hand":                    "🙋",
+    "ramen":                           "🍜",
+    "recycle":                         "♻️",
+    "red_circle":                      "🔴",
+    "red_square":                      "🟥",
+    "registered":                      "®️",
+    "relaxed":                         "☺️",
+    "relieved":                        "😌",
+    "repeat":                          "🔀",
+    "revolving_hearts":                "💞",
+    "ribbon":                          "🎀",
+    "rice":                            "🍚",
+    "ring":                            "💍",
+    "robot":                           "🤖",
+    "rocket":                          "🚀",
+    "rofl":                            "🤣",
+    "roll_eyes":                       "🙄",
+    "rose":                            "🌹",
+    "round_pushpin":                   "📍",
+    "runner":                          "🏃",
+    "running":                         "🏃",
+    "santa":                           "🎅",
+    "sauropod":                        "🦕",
+    "school":                          "🏫",
+    "school_satchel":                  "🎒",
+    "scissors":                        "✂️",
+    "scream":                          "😱",
+    "see_no_evil":                     "🙈",
+    "seedling":                        "🌱",
+    "shark":                           "🦈",
+    "shield":                          "🛡️",
+    "ship":                            "🚢",
+    "shirt":                           "👕",
+    "shoe":                            "👞",
+    "shrug":                           "🤷",
+    "shushing_face":                   "🤫",
+    "skull":                           "💀",
+    "sleeping":                        "😴",
+    "sleepy":                          "😪",
+    "slightly_frowning_face":          "🙁",
+    "slightly_smiling_face":           "🙂",
+    "small_red_triangle":              "🔺",
+    "small_red_triangle_down":         "🔻",
+    "smile":                           "😄",
+    "smiley":                          "😃",
+    "smiley_cat":                      "😺",
+    "smiling_imp":                     "😈",
+    "smirk":                           "😏",
+    "snail":                           "🐌",
+    "snake":                           "🐍",
+    "sneezing_face":                   "🤧",
+    "snowflake":                       "❄️",
+    "snowman":                         "⛄",
+    "sob":                             "😭",
+    "soccer":                          "⚽",
+    "soon":                            "🔜",
+    "sos":                             "🆘",
+    "spaghetti":                       "🍝",
+    "sparkles":                        "✨",
+    "sparkling_heart":                 "💖",
+    "speak_no_evil":                   "🙊",
+    "speech_balloon":                  "💬",
+    "spider":                          "🕷",
+    "star":                            "⭐",
+    "star2":                           "🌟",
+    "star_struck":                     "🤩",
+    "stop_sign":                       "🛑",
+    "stopwatch":                       "⏱️",
+    "straight_ruler":                  "📏",
+    "strawberry":                      "🍓",
+    "stuck_out_tongue":                "😛",
+    "stuck_out_tongue_winking_eye":    "😜",
+    "sunflower":                       "🌻",
+    "sunglasses":                      "😎",
+    "sunny":                           "☀️",
+    "sushi":                           "🍣",
+    "sweat_drops":                     "💦",
+    "sweat_smile":                     "😅",
+    "syringe":                         "💉",
+    "t-rex":                           "🦖",
+    "taco":                            "🌮",
+    "tada":                            "🎉",
+    "tangerine":                       "🍊",
+    "taxi":                            "🚕",
+    "tea":                             "🍵",
+    "telephone_receiver":              "📞",
+    "telescope":                       "🔭",
+    "tennis":                          "🎾",
+    "tent":                            "⛺",
+    "test_tube":                       "🧪",
+    "thinking":                        "🤔",
+    "thought_balloon":                 "💭",
+    "thumbsdown":                      "👎",
+    "thumbsup":                        "👍",
+    "tiger":                           "🐯",
+    "timer_clock":                     "⏲️",
+    "tired_face":                      "😫",
+    "tm":                              "™️",
+    "tomato":                          "🍅",
+    "tongue":                          "👅",
+    "toolbox":                         "🧰",
+    "top":                             "🔝",
+    "tophat":                          "🎩",
+    "tornado":                         "🌪️",
+    "train":                           "🚆",
+    "triangular_flag_on_post":         "🚩",
+    "triangular_ruler":                "📐",
+    "triumph":                         "😤",
+    "trophy":                          "🏆",
+    "tropical_fish":                   "🐠",
+    "tulip":                           "🌷",
+    "turtle":                          "🐢",
+    "tv":                              "📺",
+    "two_hearts":                      "💕",
+    "umbrella":                        "☔",
+    "unamused":                        "😒",
+    "unicorn":                         "🦄",
+    "unlock":                          "🔓",
+    "up":                              "🆙",
+    "upside_down_face":                "🙃",
+    "v":                               "✌️",
+    "vertical_traffic_light":          "🚦",
+    "video_camera":                    "📹",
+    "video_game":                      "🎮",
+    "vulcan_salute":                   "🖖",
+    "warning":                         "⚠️",
+    "wastebasket":                     "🗑️",
+    "watch":                           "⌚",
+    "watermelon":                      "🍉",
+    "wave":                            "👋",
+    "weary":                           "😩",
+    "whale":                           "🐳",
+    "white_check_mark":                "✅",
+    "white_circle":                    "⚪",
+    "white_flag":                      "🏳️",
+    "white_heart":                     "🤍",
+    "white_large_square":              "⬜",
+    "wine_glass":                      "🍷",
+    "wink":                            "😉",
+    "wolf":                            "🐺",
+    "woman":                           "👩",
+    "worried":                         "😟",
+    "wrench":                          "🔧",
+    "writing_hand":                    "✍️",
+    "x":                               "❌",
+    "yellow_circle":                   "🟡",
+    "yellow_heart":                    "💛",
+    "yum":                             "😋",
+    "zany_face":                       "🤪",
+    "zap":                             "⚡",
+    "zipper_mouth_face":               "🤐",
+    "zzz":                             "💤",
+}
@@ -14,7 +14,18 @@ package markdown
 
 import (
     "fmt"
+    "html"
+    "io"
+    "net/url"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
     "strings"
+    "sync"
+    "unicode"
+    "unicode/utf8"
 )
 
 // Flavor constants define the Markdown dialects supported by the library.
@@ -35,10 +46,147 @@ const (
 // - content: a string builder for accumulating Markdown content
 // - flavor: an integer that specifies the Markdown flavor
 // - useColor: a boolean indicating if color should be applied
+// - repoURL: the base URL of the repository used for commit links
+// - extractLinks: whether inline links are converted to reference links on output
+// - htmlOutput: whether constructs with both forms are rendered as raw HTML
+// - strictURLs: whether links and images with invalid URLs are rejected
+// - headings: the headings added so far, used for navigation
+// - slugs: the number of times each heading slug has been used
+// - strict: whether the convenience methods record validation failures
+// - err: the first recorded error; writes are skipped while it is set
+// - threadSafe: whether writes are guarded by mu
+// - mu: the mutex guarding content and tracked state in thread-safe mode
+// - out: the writer blocks are streamed to instead of content, if set
+// - deferFootnotes: whether footnotes are collected in footnotes until flushed
+// - wikiSidebar: the sidebar links recorded by WikiPage
+// - precision: the number of decimals for rendered numbers, -1 for the shortest form
+// - tildeFences: whether code blocks are fenced with tildes instead of backticks
+// - pageBreak: the raw HTML emitted by PageBreak, if overridden
+// - headingNumbers: whether headings are prefixed with computed section numbers
+// - sectionCounters: the section counters per heading level used for numbering
+// - headingOffset: the number of levels every heading is shifted by
+// - autoBackToTop: whether a back-to-top link closes each H2 section
+// - deriveTaskStatus: whether TaskTree derives parent completion from subtasks
+// - unicodeEmoji: whether Emoji outputs Unicode characters instead of shortcodes
+// - unknownEmoji: the number of unknown shortcodes passed to Emoji
+// - wrapWidth: the column at which Paragraph and Blockquote wrap text, 0 to disable
+// - smartTypography: whether Paragraph converts straight quotes and dashes to typographic ones
+// - palette: logical color names such as "primary" mapped to CSS colors
+// - escapeTemplateValues: whether Template escapes the values it substitutes
+// - included: the absolute paths of the files added by Include
+// - figures: the captioned figures, listed by ListOfFigures
+// - tables: the captioned tables, listed by ListOfTables
+// - skipHiddenFiles: whether FileTree leaves out files and directories starting with a dot
+// - citations: the keys cited with Cite, in order of first use
+// - warnings: problems noticed while rendering that are not errors, see Warnings
+// - superscriptFootnotes: whether FootnoteRef wraps footnote markers in <sup>
+// - tabStyle: the output format of Tabs
+// - baseURL: the base against which relative link and image URLs are resolved
+// - autoFootnotes: the texts of footnotes allocated by AutoFootnote that RenderFootnotes has not written yet
+// - footnoteCount: the number of labels allocated by AutoFootnote
+// - sectionDepth: the nesting depth of the running NestedSection calls
+// - blockSpacing: the number of blank lines written between top-level blocks
+// - collapsibleTOC: whether tables of contents are wrapped in a collapsible <details> element
+// - tocOptions: the level range and numbering of tables of contents
+// - glossaryTerms: the lower-cased terms defined by Glossary
+// - glossaryLinks: the terms linked with GlossaryLink before a glossary was added
+// - autoLinkURLs: whether Paragraph turns bare URLs into autolinks for flavors without automatic linking
+// - snapshots: the state recorded by Snapshot, keyed by the mark returned for it
+// - snapshotCount: the number of marks handed out by Snapshot
+// - autoEscape: whether Paragraph, Heading, lists, and tables escape Markdown special characters in their text
+// - ruleStyle: the marker HorizontalRule emits, "---" if empty
+// - alignedTables: whether Table pads cells so that the columns of the source line up
 type Markdown struct {
-    content  strings.Builder
-    flavor   int    // Stores the selected flavor
-    useColor bool   // Flag to determine if color support is enabled
+    content              strings.Builder
+    flavor               int               // Stores the selected flavor
+    useColor             bool              // Flag to determine if color support is enabled
+    repoURL              string            // Base repository URL, e.g. https://github.com/user/repo
+    extractLinks         bool              // Convert inline links to reference links in GetContent
+    htmlOutput           bool              // Prefer HTML over plain Markdown where both exist
+    strictURLs           bool              // Reject URLs that fail validation instead of passing them through
+    headings             []heading         // Tracked headings in document order
+    slugs                map[string]int    // Slug usage counts for unique anchors
+    strict               bool              // Record validation failures of void methods in err
+    err                  error             // First recorded error (sticky until cleared)
+    threadSafe           bool              // Guard writes with mu
+    mu                   sync.Mutex        // Protects the document in thread-safe mode
+    out                  io.Writer         // Streaming destination (see NewWriter)
+    deferFootnotes       bool              // Collect footnotes instead of writing them
+    footnotes            strings.Builder   // Deferred footnote definitions
+    wikiSidebar          []Link            // Links for the GitHub wiki _Sidebar.md page
+    precision            int               // Decimal places for numbers (-1 = shortest exact form)
+    tildeFences          bool              // Fence code blocks with ~ instead of `
+    pageBreak            string            // Custom page break snippet (empty = default)
+    headingNumbers       bool              // Prefix headings with section numbers
+    sectionCounters      [6]int            // Current section number per heading level
+    headingOffset        int               // Added to every heading level
+    autoBackToTop        bool              // Insert back-to-top links before H2 headings
+    deriveTaskStatus     bool              // Derive parent task completion from subtasks
+    unicodeEmoji         bool              // Render emoji shortcodes as Unicode characters
+    unknownEmoji         int               // Number of unknown emoji shortcodes emitted
+    wrapWidth            int               // Column at which prose is wrapped, 0 disables wrapping
+    smartTypography      bool              // Whether Paragraph converts quotes and dashes
+    palette              map[string]string // Logical color names resolved by ColorText
+    escapeTemplateValues bool              // Whether Template escapes substituted values
+    included             map[string]bool   // Absolute paths of the files added by Include
+    figures              []captioned       // Captioned figures, in order
+    tables               []captioned       // Captioned tables, in order
+    skipHiddenFiles      bool              // Whether FileTree leaves out hidden files
+    citations            []string          // Keys cited with Cite, in order of first use
+    warnings             []string          // Problems found that are not errors
+    superscriptFootnotes bool              // Whether FootnoteRef wraps markers in <sup>
+    tabStyle             TabStyle          // Output format of Tabs
+    baseURL              *url.URL          // Base against which relative link and image URLs are resolved
+    autoFootnotes        []string          // Footnotes allocated by AutoFootnote and not yet rendered
+    footnoteCount        int               // Number of labels allocated by AutoFootnote
+    sectionDepth         int               // Nesting depth of NestedSection calls
+    blockSpacing         int               // Number of blank lines between top-level blocks
+    collapsibleTOC       bool              // Whether tables of contents are wrapped in <details>
+    tocOptions           TOCOptions        // Level range and numbering of tables of contents
+    glossaryTerms        map[string]bool   // Terms defined by Glossary, lower-cased
+    glossaryLinks        []string          // Terms linked with GlossaryLink before a glossary was added
+    autoLinkURLs         bool              // Whether Paragraph wraps bare URLs in angle brackets
+    snapshots            map[int]snapshot  // State recorded by Snapshot, by mark
+    snapshotCount        int               // Number of marks handed out by Snapshot
+    autoEscape           bool              // Whether block methods escape the text they are given
+    ruleStyle            string            // Marker of horizontal rules (empty = ---)
+    alignedTables        bool              // Whether pipe tables are padded to aligned columns
+}
+
+// heading records a heading added to the document.
+type heading struct {
+    level    int
+    text     string // The text as written, including section numbers and escapes
+    raw      string // The text as given by the caller
+    id       string // The explicit ID or the generated slug
+    numbered bool   // Whether text starts with a section number
+}
+
+// captioned records a figure or table with a caption.
+type captioned struct {
+    id      string
+    caption string
+}
+
+// snapshot records the state of a document at a Snapshot mark, so that
+// RestoreTo can roll back the content together with the tracking state.
+type snapshot struct {
+    content         int // Length of the content
+    headings        int
+    slugs           map[string]int
+    sectionCounters [6]int
+    footnotes       int
+    autoFootnotes   []string // Copied, as RenderFootnotes clears them
+    footnoteCount   int
+    figures         int
+    tables          int
+    citations       int
+    wikiSidebar     int
+    warnings        int
+    glossaryTerms   map[string]bool // Copied, as Glossary adds to it
+    glossaryLinks   []string        // Copied, as Glossary clears them
+    included        map[string]bool // Copied, as Include adds to it
+    unknownEmoji    int
 }
 
 // New initializes a new Markdown instance with the specified flavor and color setting.
@@ -50,7 +198,400 @@ type Markdown struct {
 // Returns:
 // - *Markdown: A pointer to the initialized Markdown structure
 func New(flavor int, useColor bool) *Markdown {
-    return &Markdown{flavor: flavor, useColor: useColor}
+    return &Markdown{flavor: flavor, useColor: useColor, precision: -1, blockSpacing: 1}
+}
+
+// WithBlockSpacing sets the number of blank lines written between top-level
+// blocks. The default is one blank line; zero separates blocks by a single
+// newline, e.g. for targets that treat every line as a block. Content nested in
+// other blocks, such as alert bodies, always uses one blank line.
+//
+// Parameters:
+// - lines: The number of blank lines between blocks, at least 0
+//
+// Returns:
+// - *Markdown: The document itself, to allow chaining
+func (md *Markdown) WithBlockSpacing(lines int) *Markdown {
+    if lines < 0 {
+        lines = 0
+    }
+    md.blockSpacing = lines
+    return md
+}
+
+// SetRepoURL sets the base URL of the repository that commit hashes link to.
+//
+// Parameters:
+// - url: The repository URL, e.g. "https://github.com/user/repo"
+func (md *Markdown) SetRepoURL(url string) {
+    md.repoURL = strings.TrimSuffix(url, "/")
+}
+
+// SetExtractInlineLinks enables or disables the conversion of inline links to
+// reference-style links when the content is retrieved. The link definitions are
+// collected at the bottom of the document, and identical URLs share one definition.
+//
+// Parameters:
+// - enabled: Whether inline links should be extracted on output
+func (md *Markdown) SetExtractInlineLinks(enabled bool) {
+    md.extractLinks = enabled
+}
+
+// SetHTMLOutput selects raw HTML rendering for constructs that have both an HTML
+// and a plain Markdown form, such as metric cards. Plain Markdown is the default.
+//
+// Parameters:
+// - enabled: Whether HTML output should be preferred
+func (md *Markdown) SetHTMLOutput(enabled bool) {
+    md.htmlOutput = enabled
+}
+
+// SetPrecision sets the number of decimal places used when rendering numbers,
+// such as conversion factors and metric deltas. A negative value selects the
+// shortest representation that preserves the exact value, which is the default.
+//
+// Parameters:
+// - digits: The number of decimal places
+func (md *Markdown) SetPrecision(digits int) {
+    if digits < 0 {
+        digits = -1
+    }
+    md.precision = digits
+}
+
+// formatNumber renders f with the configured precision.
+func (md *Markdown) formatNumber(f float64) string {
+    return strconv.FormatFloat(f, 'f', md.precision, 64)
+}
+
+// SetHeadingOffset shifts all subsequent headings by n levels, e.g. to embed a
+// generated section in a larger document. The resulting level is clamped to 1-6.
+//
+// Parameters:
+// - n: The number of levels to add to each heading level (may be negative)
+func (md *Markdown) SetHeadingOffset(n int) {
+    md.headingOffset = n
+}
+
+// SetAutoBackToTop enables or disables the automatic insertion of a "Back to
+// top" link before every H2 heading except the first, so that each section
+// ends with a link to the first heading of the document.
+//
+// Parameters:
+// - enabled: Whether back-to-top links should be inserted automatically
+func (md *Markdown) SetAutoBackToTop(enabled bool) {
+    md.autoBackToTop = enabled
+}
+
+// WithHeadingNumbers enables or disables automatic section numbering. When
+// enabled, each heading is prefixed with its hierarchical number, e.g.
+// "## 1.1 Overview", and deeper counters restart whenever a higher-level
+// heading appears.
+//
+// Parameters:
+// - enabled: Whether headings should be numbered
+//
+// Returns:
+// - *Markdown: The document itself, to allow chaining
+func (md *Markdown) WithHeadingNumbers(enabled bool) *Markdown {
+    md.headingNumbers = enabled
+    return md
+}
+
+// SetStrictURLs enables strict URL validation. In strict mode, Link and Image skip
+// URLs that cannot be parsed or that use a scheme other than http, https, mailto,
+// or tel. Relative URLs are always allowed.
+//
+// Parameters:
+// - enabled: Whether invalid URLs should be rejected
+func (md *Markdown) SetStrictURLs(enabled bool) {
+    md.strictURLs = enabled
+}
+
+// WithThreadSafe enables or disables locking so that a document can be written
+// from multiple goroutines. In thread-safe mode every block is appended
+// atomically and GetContent takes the lock as well. Without it (the default)
+// no locking takes place. The setting must be chosen before the document is
+// shared between goroutines.
+//
+// Parameters:
+// - enabled: Whether writes should be guarded by a mutex
+//
+// Returns:
+// - *Markdown: The document itself, to allow chaining
+func (md *Markdown) WithThreadSafe(enabled bool) *Markdown {
+    md.threadSafe = enabled
+    return md
+}
+
+// lock acquires the document mutex in thread-safe mode.
+func (md *Markdown) lock() {
+    if md.threadSafe {
+        md.mu.Lock()
+    }
+}
+
+// unlock releases the document mutex in thread-safe mode.
+func (md *Markdown) unlock() {
+    if md.threadSafe {
+        md.mu.Unlock()
+    }
+}
+
+// SetStrict enables strict mode. In strict mode, methods without an error result
+// record the first validation failure instead of silently skipping the input.
+// Once an error is recorded, all further writes are skipped until ClearErr is
+// called, so a chain of calls can be checked once at the end via Err.
+//
+// Parameters:
+// - enabled: Whether validation failures should be recorded
+func (md *Markdown) SetStrict(enabled bool) {
+    md.strict = enabled
+}
+
+// Err returns the first error recorded in strict mode.
+//
+// Returns:
+// - error: The first recorded error, or nil if there is none
+func (md *Markdown) Err() error {
+    md.lock()
+    defer md.unlock()
+    return md.err
+}
+
+// ClearErr discards the recorded error so that writes take effect again.
+func (md *Markdown) ClearErr() {
+    md.lock()
+    md.err = nil
+    md.unlock()
+}
+
+// check records err as the document error if strict mode is enabled and no
+// error has been recorded yet.
+func (md *Markdown) check(err error) {
+    if err == nil || !md.strict {
+        return
+    }
+    md.lock()
+    if md.err == nil {
+        md.err = err
+    }
+    md.unlock()
+}
+
+// write appends s to the content unless an error has been recorded. Blocks are
+// passed to write as a whole, so each block is appended atomically. When the
+// document streams to a writer, s is written there instead and write errors are
+// recorded regardless of strict mode. The blank line ending a block is adjusted
+// to the block spacing.
+func (md *Markdown) write(s string) {
+    md.writeSpaced(s, true)
+}
+
+// writeHTMLOpen writes the opening tags of an HTML block that wraps Markdown
+// content. The blank line after them is kept regardless of the block spacing,
+// since without it the Markdown content would become part of the HTML block.
+func (md *Markdown) writeHTMLOpen(s string) {
+    md.writeSpaced(s, false)
+}
+
+// writeHTMLClose writes the closing tags of an HTML block that wraps Markdown
+// content. At least one blank line follows them regardless of the block
+// spacing, since without it the next block would become part of the HTML block.
+func (md *Markdown) writeHTMLClose(s string) {
+    if md.blockSpacing == 0 {
+        md.writeHTMLOpen(s + "\n")
+        return
+    }
+    md.write(s + "\n")
+}
+
+// writeSpaced implements write, adjusting the blank line ending s to the block
+// spacing only if adjust is set.
+func (md *Markdown) writeSpaced(s string, adjust bool) {
+    md.lock()
+    defer md.unlock()
+    if md.err != nil {
+        return // Sticky error: skip writes until cleared
+    }
+    if adjust && md.blockSpacing != 1 && strings.HasSuffix(s, "\n\n") {
+        s = strings.TrimSuffix(s, "\n") + strings.Repeat("\n", md.blockSpacing)
+    }
+    if md.out != nil {
+        if _, err := io.WriteString(md.out, s); err != nil {
+            md.err = err
+        }
+        return
+    }
+    md.content.WriteString(s)
+}
+
+// Prepend inserts text as a block before all other content, e.g. for a summary
+// that can only be computed after the body is built. Streamed content cannot be
+// changed, so in streaming mode Prepend is an error in strict mode and ignored
+// otherwise.
+//
+// Parameters:
+// - text: The content to insert at the top of the document
+func (md *Markdown) Prepend(text string) {
+    text = strings.TrimRight(text, "\n")
+    if text == "" {
+        return // Skip empty content
+    }
+    if md.out != nil {
+        md.check(fmt.Errorf("markdown: cannot prepend to a streamed document"))
+        return
+    }
+    md.lock()
+    defer md.unlock()
+    if md.err != nil {
+        return // Sticky error: skip writes until cleared
+    }
+    content := md.content.String()
+    prefix := text + "\n" + strings.Repeat("\n", md.blockSpacing)
+    md.content.Reset()
+    md.content.WriteString(prefix + content)
+    for m, state := range md.snapshots {
+        state.content += len(prefix) // Restoring keeps the prepended block
+        md.snapshots[m] = state
+    }
+}
+
+// Snapshot marks the current state of the document, e.g. to add blocks
+// speculatively and roll them back with RestoreTo if they turn out not to be
+// needed. Besides the content, tracked headings, footnotes, captions,
+// citations, warnings, glossary terms, and included files are recorded so that
+// RestoreTo can roll them back as well. Each
+// call returns a new mark, even if nothing was added in between.
+//
+// Returns:
+// - int: The mark identifying the snapshot
+func (md *Markdown) Snapshot() int {
+    md.lock()
+    defer md.unlock()
+    if md.snapshots == nil {
+        md.snapshots = make(map[int]snapshot)
+    }
+    md.snapshotCount++
+    md.snapshots[md.snapshotCount] = snapshot{
+        content:         md.content.Len(),
+        headings:        len(md.headings),
+        slugs:           copySlugs(md.slugs),
+        sectionCounters: md.sectionCounters,
+        footnotes:       md.footnotes.Len(),
+        autoFootnotes:   append([]string(nil), md.autoFootnotes...),
+        footnoteCount:   md.footnoteCount,
+        figures:         len(md.figures),
+        tables:          len(md.tables),
+        citations:       len(md.citations),
+        wikiSidebar:     len(md.wikiSidebar),
+        warnings:        len(md.warnings),
+        glossaryTerms:   copySet(md.glossaryTerms),
+        glossaryLinks:   append([]string(nil), md.glossaryLinks...),
+        included:        copySet(md.included),
+        unknownEmoji:    md.unknownEmoji,
+    }
+    return md.snapshotCount
+}
+
+// RestoreTo rolls the document back to a mark returned by Snapshot,
+// discarding everything added since, including headings, deferred footnotes,
+// captions, citations, warnings, glossary terms, and included files. Marks taken after the restored one become invalid;
+// the restored mark stays valid. Streamed content cannot be taken back, so in
+// streaming mode RestoreTo is an error in strict mode and ignored otherwise, as
+// is an unknown mark.
+//
+// Parameters:
+// - mark: The mark returned by Snapshot
+func (md *Markdown) RestoreTo(mark int) {
+    if md.out != nil {
+        md.check(fmt.Errorf("markdown: cannot restore a streamed document"))
+        return
+    }
+    md.lock()
+    state, ok := md.snapshots[mark]
+    if !ok || state.content > md.content.Len() {
+        md.unlock()
+        md.check(fmt.Errorf("markdown: invalid snapshot mark %d", mark))
+        return
+    }
+    defer md.unlock()
+    content := md.content.String()[:state.content]
+    md.content.Reset()
+    md.content.WriteString(content)
+    md.headings = md.headings[:state.headings]
+    md.slugs = copySlugs(state.slugs) // The mark stays valid for another RestoreTo
+    md.sectionCounters = state.sectionCounters
+    footnotes := md.footnotes.String()[:state.footnotes]
+    md.footnotes.Reset()
+    md.footnotes.WriteString(footnotes)
+    md.autoFootnotes = append([]string(nil), state.autoFootnotes...)
+    md.footnoteCount = state.footnoteCount
+    md.figures = md.figures[:state.figures]
+    md.tables = md.tables[:state.tables]
+    md.citations = md.citations[:state.citations]
+    md.wikiSidebar = md.wikiSidebar[:state.wikiSidebar]
+    md.warnings = md.warnings[:state.warnings]
+    md.glossaryTerms = copySet(state.glossaryTerms)
+    md.glossaryLinks = append([]string(nil), state.glossaryLinks...)
+    md.included = copySet(state.included)
+    md.unknownEmoji = state.unknownEmoji
+    for m := range md.snapshots {
+        if m > mark {
+            delete(md.snapshots, m) // Taken after the mark, so no longer valid
+        }
+    }
+}
+
+// ContentSince returns the content appended after a mark returned by Snapshot,
+// e.g. to stream newly added blocks without resending the whole document.
+// Unlike GetContent it returns the content as written, without link
+// extraction. An unknown mark yields "".
+//
+// Parameters:
+// - mark: The mark returned by Snapshot
+//
+// Returns:
+// - string: The content added since the mark
+func (md *Markdown) ContentSince(mark int) string {
+    md.lock()
+    defer md.unlock()
+    state, ok := md.snapshots[mark]
+    content := md.content.String()
+    if !ok || state.content > len(content) {
+        return ""
+    }
+    return content[state.content:]
+}
+
+// copySlugs returns a copy of the slug usage counts.
+func copySlugs(slugs map[string]int) map[string]int {
+    c := make(map[string]int, len(slugs))
+    for slug, n := range slugs {
+        c[slug] = n
+    }
+    return c
+}
+
+// copySet returns a copy of a set of strings, keeping nil as nil.
+func copySet(set map[string]bool) map[string]bool {
+    if set == nil {
+        return nil
+    }
+    c := make(map[string]bool, len(set))
+    for key := range set {
+        c[key] = true
+    }
+    return c
+}
+
+// needsBlankLine reports whether the content written so far ends without a
+// blank line, so that a block appended now would continue the previous one.
+func (md *Markdown) needsBlankLine() bool {
+    md.lock()
+    defer md.unlock()
+    content := md.content.String()
+    return content != "" && !strings.HasSuffix(content, "\n\n")
 }
 
 // FrontMatter adds YAML metadata for the Markdown document. Typical keys include
@@ -59,14 +600,16 @@ func New(flavor int, useColor bool) *Markdown {
 // Parameters:
 // - metadata: A map of metadata keys to values
 func (md *Markdown) FrontMatter(metadata map[string]string) {
-    md.content.WriteString("---\n")
+    var b strings.Builder
+    b.WriteString("---\n")
     keys := []string{"title", "author", "date"}
     for _, key := range keys {
         if value, exists := metadata[key]; exists {
-            md.content.WriteString(fmt.Sprintf("%s: \"%s\"\n", key, value))
+            b.WriteString(fmt.Sprintf("%s: \"%s\"\n", key, value))
         }
     }
-    md.content.WriteString("---\n\n")
+    b.WriteString("---\n\n")
+    md.write(b.String())
 }
 
 // Heading inserts a Markdown heading at the specified level with optional ID and attributes.
@@ -77,304 +620,3586 @@ func (md *Markdown) FrontMatter(metadata map[string]string) {
 // - id: An optional ID for linking to the heading
 // - attributes: Optional attributes for the heading, e.g., CSS classes
 func (md *Markdown) Heading(level int, text, id, attributes string) {
-    if level < 1 || level > 6 {
-        level = 1 // default to level 1
+    md.check(md.HeadingE(level, text, id, attributes))
+}
+
+// HeadingE works like Heading but returns an error instead of silently skipping
+// invalid input.
+//
+// Parameters:
+// - level: The heading level (1-6, with 1 being the largest)
+// - text: The text for the heading
+// - id: An optional ID for linking to the heading
+// - attributes: Optional attributes for the heading, e.g., CSS classes
+//
+// Returns:
+// - error: A description of why the heading was rejected, or nil
+func (md *Markdown) HeadingE(level int, text, id, attributes string) error {
+    if err := md.Err(); err != nil {
+        return err
     }
-    if text == "" {
-        return // Do not allow empty headings
+    if err := validateHeading(text); err != nil {
+        return err
     }
-    header := fmt.Sprintf("%s %s", strings.Repeat("#", level), text)
+    suffix := ""
     if id != "" {
-        header += fmt.Sprintf(" {#%s}", id)
+        suffix += fmt.Sprintf(" {#%s}", id)
     }
     if attributes != "" {
-        header += fmt.Sprintf(" {%s}", attributes)
+        suffix += fmt.Sprintf(" {%s}", attributes)
     }
-    md.content.WriteString(header + "\n\n")
+    md.writeHeading(level, text, id, suffix)
+    return nil
 }
 
-// ApplyFormatting applies multiple Markdown formatting options to the given text.
+// HeadingWithAttrs inserts a heading with an attribute block assembled from
+// its parts, e.g. {#intro .lead .wide data-level="1"}. Classes keep their
+// order and key-value attributes are sorted by key, so the output is
+// deterministic. An ID or class containing whitespace rejects the heading,
+// which is recorded as an error in strict mode.
 //
 // Parameters:
-// - text: The text to format
-// - formats: A variable number of format strings, e.g., "bold", "italic"
+// - level: The heading level (1-6, with 1 being the largest)
+// - text: The text for the heading
+// - id: An optional ID for linking to the heading
+// - classes: Optional CSS classes
+// - attrs: Optional key-value attributes; "id" and "class" keys are ignored
+func (md *Markdown) HeadingWithAttrs(level int, text, id string, classes []string, attrs map[string]string) {
+    md.check(md.HeadingWithAttrsE(level, text, id, classes, attrs))
+}
+
+// HeadingWithAttrsE works like HeadingWithAttrs but returns an error instead of
+// silently skipping invalid input.
+//
+// Parameters:
+// - level: The heading level (1-6, with 1 being the largest)
+// - text: The text for the heading
+// - id: An optional ID for linking to the heading
+// - classes: Optional CSS classes
+// - attrs: Optional key-value attributes; "id" and "class" keys are ignored
 //
 // Returns:
-// - string: The formatted text as a Markdown string
-func (md *Markdown) ApplyFormatting(text string, formats ...string) string {
-    for i := len(formats) - 1; i >= 0; i-- {
-        switch formats[i] {
-        case "strikethrough":
-            text = "~~" + text + "~~"
-        case "bold":
-            text = "**" + text + "**"
-        case "italic":
-            text = "_" + text + "_"
-        case "underline":
-            text = "<u>" + text + "</u>"
-        case "subscript":
-            text = "<sub>" + text + "</sub>"
-        case "superscript":
-            text = "<sup>" + text + "</sup>"
-        case "code":
-            text = "`" + text + "`"
+// - error: A description of why the heading was rejected, or nil
+func (md *Markdown) HeadingWithAttrsE(level int, text, id string, classes []string, attrs map[string]string) error {
+    if err := md.Err(); err != nil {
+        return err
+    }
+    if err := validateHeading(text); err != nil {
+        return err
+    }
+    if strings.ContainsAny(id, " \t\n") {
+        return fmt.Errorf("markdown: heading ID %q contains whitespace", id)
+    }
+    all := map[string]string{"id": id}
+    for _, class := range classes {
+        if class == "" || strings.ContainsAny(class, " \t\n") {
+            return fmt.Errorf("markdown: heading class %q is empty or contains whitespace", class)
         }
     }
-    return text
+    all["class"] = strings.Join(classes, " ")
+    for key, value := range attrs {
+        if key == "id" || key == "class" {
+            continue // Set from the dedicated parameters
+        }
+        if strings.ContainsAny(key, " \t\n") {
+            return fmt.Errorf("markdown: heading attribute %q contains whitespace", key)
+        }
+        all[key] = value
+    }
+    suffix := ""
+    if attributes := pandocAttributes(all); attributes != "{}" {
+        suffix = " " + attributes
+    }
+    md.writeHeading(level, text, id, suffix)
+    return nil
 }
 
-// Paragraph inserts a paragraph into the Markdown document with optional formatting.
-//
-// Parameters:
-// - text: The text content of the paragraph
-// - formats: Optional formatting, such as "bold" or "italic"
-func (md *Markdown) Paragraph(text string, formats ...string) {
-    if text == "" {
-        return // Skip empty paragraphs
+// writeHeading writes a heading whose text is followed by suffix, a rendered
+// attribute block or "".
+func (md *Markdown) writeHeading(level int, text, id, suffix string) {
+    header, _, backToTop := md.headingLine(level, text, id, suffix)
+    if backToTop {
+        md.BackToTop("") // Close the previous section
     }
-    formatted := md.ApplyFormatting(text, formats...)
-    md.content.WriteString(formatted + "\n\n")
+    md.write(header)
 }
 
-// CodeBlock inserts a code block with optional syntax highlighting for a specified language.
-//
-// Parameters:
-// - language: The programming language for syntax highlighting (e.g., "go", "python")
-// - code: The code content to include in the block
-func (md *Markdown) CodeBlock(language, code string) {
-    if code == "" {
-        return // Skip empty code blocks
+// headingLine tracks a heading and returns the block that renders it without
+// writing it, together with its ID, so that links to the heading can be
+// written first. backToTop reports whether a back-to-top link has to precede
+// the block.
+func (md *Markdown) headingLine(level int, text, id, suffix string) (header, headingID string, backToTop bool) {
+    if level < 1 || level > 6 {
+        level = 1 // default to level 1
     }
-    md.content.WriteString(fmt.Sprintf("```%s\n%s\n```\n\n", language, code))
+    level = clampLevel(level + md.headingOffset)
+    raw := text
+    text = md.escapeText(text)
+    if md.headingNumbers {
+        text = md.sectionNumber(level) + " " + text
+    }
+    header = fmt.Sprintf("%s %s", strings.Repeat("#", level), text) + suffix + "\n\n"
+    backToTop = md.autoBackToTop && level == 2 && md.hasHeading(2)
+    return header, md.trackHeading(level, raw, text, id), backToTop
 }
 
-// ReferenceLink creates a Markdown reference link with a label, text, and URL.
-//
-// Parameters:
-// - label: The reference label
-// - text: The visible link text
-// - url: The destination URL
-func (md *Markdown) ReferenceLink(label, text, url string) {
-    if label == "" || text == "" || url == "" {
-        return // Skip invalid reference links
+// hasHeading reports whether a heading of the given level has been added.
+func (md *Markdown) hasHeading(level int) bool {
+    md.lock()
+    defer md.unlock()
+    for _, h := range md.headings {
+        if h.level == level {
+            return true
+        }
     }
-    md.content.WriteString(fmt.Sprintf("[%s]: %s\n", label, text))
-    md.content.WriteString(fmt.Sprintf("[%s](%s)\n\n", text, url))
+    return false
 }
 
-// Image inserts an image with alt text and a source URL.
+// BackToTop inserts a link back to the top of the document.
 //
 // Parameters:
-// - altText: Alternative text for the image
-// - url: The image source URL
-func (md *Markdown) Image(altText, url string) {
-    if altText == "" || url == "" {
-        return // Skip invalid image entries
+// - anchor: The anchor to link to, or "" for the first heading of the document
+func (md *Markdown) BackToTop(anchor string) {
+    if anchor == "" {
+        md.lock()
+        if len(md.headings) > 0 {
+            anchor = md.headings[0].id
+        }
+        md.unlock()
     }
-    md.content.WriteString(fmt.Sprintf("![%s](%s)\n\n", altText, url))
+    if anchor == "" {
+        return // Skip links without a target
+    }
+    md.write(fmt.Sprintf("[Back to top](#%s)\n\n", anchor))
 }
 
-// List generates a Markdown list (ordered or unordered).
-//
-// Parameters:
-// - items: A slice of strings representing each list item
-// - isOrdered: If true, creates an ordered list; otherwise, an unordered list
-func (md *Markdown) List(items []string, isOrdered bool) {
-    if len(items) == 0 {
-        return // Skip empty lists
+// clampLevel limits a heading level to the range 1-6.
+func clampLevel(level int) int {
+    if level < 1 {
+        return 1
     }
-    for i, item := range items {
-        if isOrdered {
-            md.content.WriteString(fmt.Sprintf("%d. %s\n", i+1, item))
+    if level > 6 {
+        return 6
+    }
+    return level
+}
+
+// sectionNumber advances the section counters for a heading at level and
+// returns its dotted number, e.g. "1.2". Counters of deeper levels are reset.
+// Levels above the first heading used are omitted, and skipped intermediate
+// levels count as 1.
+func (md *Markdown) sectionNumber(level int) string {
+    md.lock()
+    defer md.unlock()
+    md.sectionCounters[level-1]++
+    for i := level; i < len(md.sectionCounters); i++ {
+        md.sectionCounters[i] = 0
+    }
+    var parts []string
+    for _, n := range md.sectionCounters[:level] {
+        if n == 0 && len(parts) == 0 {
+            continue // Level above the outermost numbered heading
+        }
+        if n == 0 {
+            n = 1
+        }
+        parts = append(parts, strconv.Itoa(n))
+    }
+    return strings.Join(parts, ".")
+}
+
+// trackHeading records a heading for navigation, generating a unique slug as
+// its anchor when no explicit ID is given, and returns the ID.
+func (md *Markdown) trackHeading(level int, raw, text, id string) string {
+    md.lock()
+    defer md.unlock()
+    if id == "" {
+        id = slugify(text)
+        if md.slugs == nil {
+            md.slugs = map[string]int{}
+        }
+        if n := md.slugs[id]; n > 0 {
+            md.slugs[id] = n + 1
+            id = fmt.Sprintf("%s-%d", id, n)
         } else {
-            md.content.WriteString(fmt.Sprintf("- %s\n", item))
+            md.slugs[id] = 1
         }
     }
-    md.content.WriteString("\n")
+    md.headings = append(md.headings, heading{level: level, text: text, raw: raw, id: id, numbered: md.headingNumbers})
+    return id
 }
 
-// NestedList creates a nested list in Markdown format.
+// Ref returns a link to a previously added heading, found by its text, so that
+// internal links need not spell out anchors. The link uses the heading's ID or
+// slug, including the suffix of duplicate headings. If several headings share
+// the text, the first one is used.
 //
 // Parameters:
-// - nestedItems: A 2D slice of strings, where each sub-slice represents a nested list
-// - isOrdered: If true, creates an ordered nested list; otherwise, unordered
-func (md *Markdown) NestedList(nestedItems [][]string, isOrdered bool) {
-    if len(nestedItems) == 0 {
-        return // Skip empty nested lists
+// - headingText: The text of the heading to link to
+//
+// Returns:
+// - string: The link, or "" if no such heading exists (an error in strict mode)
+func (md *Markdown) Ref(headingText string) string {
+    md.lock()
+    id := ""
+    for _, h := range md.headings {
+        if h.raw == headingText {
+            id = h.id
+            break
+        }
     }
-    for i, items := range nestedItems {
-        if isOrdered {
-            for _, item := range items {
-                md.content.WriteString(fmt.Sprintf("%d. %s\n", i+1, item))
-            }
-        } else {
-            for j, item := range items {
-                if j == 0 {
-                    md.content.WriteString(fmt.Sprintf("- %s\n", item)) // First item
-                } else {
-                    md.content.WriteString(fmt.Sprintf("  - %s\n", item)) // Nested items
-                }
-            }
+    md.unlock()
+    if id == "" {
+        md.check(fmt.Errorf("markdown: no heading %q to reference", headingText))
+        return ""
+    }
+    return fmt.Sprintf("[%s](#%s)", headingText, id)
+}
+
+// slugify converts heading text into an anchor following GitHub's rules:
+// lowercase letters, digits, hyphens, and underscores are kept, spaces become
+// hyphens, and everything else is dropped.
+func slugify(text string) string {
+    var b strings.Builder
+    for _, r := range strings.ToLower(text) {
+        switch {
+        case r == ' ':
+            b.WriteRune('-')
+        case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+            b.WriteRune(r)
         }
     }
-    md.content.WriteString("\n")
+    return b.String()
+}
+
+// navEntry is a heading placed at a nesting depth within a navigation list.
+type navEntry struct {
+    depth int
+    heading
+}
+
+// outline returns the tracked headings from minLevel to maxLevel with their
+// nesting depth. Depths are relative to the shallowest included level and never
+// increase by more than one from one entry to the next, so skipped levels nest
+// cleanly.
+func (md *Markdown) outline(minLevel, maxLevel int) []navEntry {
+    md.lock()
+    defer md.unlock()
+    shallowest := 7
+    for _, h := range md.headings {
+        if h.level >= minLevel && h.level <= maxLevel && h.level < shallowest {
+            shallowest = h.level
+        }
+    }
+    var entries []navEntry
+    for _, h := range md.headings {
+        if h.level < minLevel || h.level > maxLevel {
+            continue
+        }
+        depth := h.level - shallowest
+        if len(entries) == 0 {
+            depth = 0
+        } else if prev := entries[len(entries)-1].depth; depth > prev+1 {
+            depth = prev + 1
+        }
+        entries = append(entries, navEntry{depth: depth, heading: h})
+    }
+    return entries
+}
+
+// SidebarNav renders the headings added so far as a navigation tree. In HTML
+// output mode the tree is a nested <nav><ul> structure; otherwise it is a
+// nested bullet list of links.
+//
+// Parameters:
+// - maxLevel: The deepest heading level to include (1-6)
+func (md *Markdown) SidebarNav(maxLevel int) {
+    entries := md.outline(1, maxLevel)
+    if len(entries) == 0 {
+        return // Skip navigation without headings
+    }
+    var b strings.Builder
+    if !md.htmlOutput {
+        for _, e := range entries {
+            b.WriteString(fmt.Sprintf("%s- [%s](#%s)\n", strings.Repeat("  ", e.depth), e.text, e.id))
+        }
+        md.write(b.String() + "\n")
+        return
+    }
+    b.WriteString("<nav>\n<ul>\n")
+    depth := 0
+    for i, e := range entries {
+        if i > 0 {
+            if e.depth > depth {
+                b.WriteString("\n<ul>\n") // Open a nested list inside the current item
+            } else {
+                b.WriteString("</li>\n")
+                for ; depth > e.depth; depth-- {
+                    b.WriteString("</ul>\n</li>\n")
+                }
+            }
+        }
+        depth = e.depth
+        b.WriteString(fmt.Sprintf("<li><a href=\"#%s\">%s</a>", html.EscapeString(e.id), html.EscapeString(e.text)))
+    }
+    b.WriteString("</li>\n")
+    for ; depth > 0; depth-- {
+        b.WriteString("</ul>\n</li>\n")
+    }
+    b.WriteString("</ul>\n</nav>\n\n")
+    md.write(b.String())
+}
+
+// TableOfContents inserts a nested list of links to the headings added so far.
+// Use TOCPlaceholder and RenderTOC to include headings added later.
+func (md *Markdown) TableOfContents() {
+    if toc := md.toc(); toc != "" {
+        md.write(toc + "\n\n")
+    }
+}
+
+// SetCollapsibleTOC controls whether tables of contents are wrapped in a
+// <details> element, so that readers can fold them away in long documents.
+//
+// Parameters:
+// - enabled: Whether tables of contents are collapsible
+func (md *Markdown) SetCollapsibleTOC(enabled bool) {
+    md.collapsibleTOC = enabled
+}
+
+// tocMarker marks the position where RenderTOC inserts the table of contents.
+const tocMarker = "<!-- toc -->"
+
+// TOCPlaceholder marks the position of the table of contents, which RenderTOC
+// fills in once all headings have been added. The marker is an HTML comment, so
+// it stays invisible if RenderTOC is never called.
+func (md *Markdown) TOCPlaceholder() {
+    md.write(tocMarker + "\n\n")
+}
+
+// RenderTOC replaces the marker written by TOCPlaceholder with a nested list of
+// links to all headings of the document. Markers inside code are ignored.
+// Without a marker, or in streaming mode where written content cannot be
+// changed, nothing happens; in strict mode this is recorded as an error. Marks
+// returned by Snapshot keep pointing at the same content.
+func (md *Markdown) RenderTOC() {
+    toc := md.toc()
+    md.lock()
+    defer md.unlock()
+    if md.err != nil {
+        return // Sticky error: skip writes until cleared
+    }
+    content := md.content.String()
+    start := tocMarkerIndex(content)
+    if start < 0 {
+        if md.strict {
+            md.err = fmt.Errorf("markdown: no table of contents placeholder")
+        }
+        return
+    }
+    end := start + len(tocMarker)
+    if toc == "" {
+        for end < len(content) && content[end] == '\n' {
+            end++ // Remove the placeholder block entirely
+        }
+    }
+    md.content.Reset()
+    md.content.WriteString(content[:start] + toc + content[end:])
+    for m, state := range md.snapshots {
+        switch {
+        case state.content >= end:
+            state.content += len(toc) - (end - start) // Taken after the marker
+        case state.content > start:
+            state.content = start // Taken inside the marker
+        }
+        md.snapshots[m] = state
+    }
+}
+
+// tocMarkerIndex returns the offset of the first line of content that holds
+// only the table of contents marker, outside of fenced code blocks, or -1 if
+// there is none. Markers quoted in code are left alone.
+func tocMarkerIndex(content string) int {
+    var fences fenceState
+    offset := 0
+    for _, line := range strings.SplitAfter(content, "\n") {
+        if fences.scan(line) == proseLine && strings.TrimSpace(line) == tocMarker {
+            return offset + strings.Index(line, tocMarker)
+        }
+        offset += len(line)
+    }
+    return -1
+}
+
+// TOCOptions controls which headings tables of contents include and how they
+// are labelled. Zero levels stand for the full range of 1 to 6.
+type TOCOptions struct {
+    MinLevel int  // The shallowest heading level to include
+    MaxLevel int  // The deepest heading level to include
+    Numbered bool // Whether entries are prefixed with numbers such as "2.1"
+}
+
+// SetTOCOptions sets the level range and numbering of the tables of contents
+// produced by TableOfContents and RenderTOC.
+//
+// Parameters:
+// - opts: The table of contents options
+func (md *Markdown) SetTOCOptions(opts TOCOptions) {
+    md.tocOptions = opts
+}
+
+// toc renders the table of contents of the headings added so far as a nested
+// list of links, without trailing newlines.
+func (md *Markdown) toc() string {
+    minLevel, maxLevel := md.tocOptions.MinLevel, md.tocOptions.MaxLevel
+    if minLevel < 1 {
+        minLevel = 1
+    }
+    if maxLevel < 1 || maxLevel > 6 {
+        maxLevel = 6
+    }
+    var b strings.Builder
+    var counters [6]int
+    for _, e := range md.outline(minLevel, maxLevel) {
+        number := ""
+        if md.tocOptions.Numbered {
+            counters[e.depth]++
+            for i := e.depth + 1; i < len(counters); i++ {
+                counters[i] = 0
+            }
+            parts := make([]string, e.depth+1)
+            for i := range parts {
+                parts[i] = strconv.Itoa(counters[i])
+            }
+            number = strings.Join(parts, ".") + " "
+            if e.numbered {
+                number = "" // The heading text has its section number already
+            }
+        }
+        b.WriteString(fmt.Sprintf("%s- %s[%s](#%s)\n", strings.Repeat("  ", e.depth), number, e.text, e.id))
+    }
+    toc := strings.TrimSuffix(b.String(), "\n")
+    if toc != "" && md.collapsibleTOC {
+        toc = "<details>\n<summary>Table of Contents</summary>\n\n" + toc + "\n\n</details>"
+    }
+    return toc
+}
+
+// ApplyFormatting applies multiple Markdown formatting options to the given text.
+//
+// Parameters:
+// - text: The text to format
+// - formats: A variable number of format strings, e.g., "bold", "italic"
+//
+// Returns:
+// - string: The formatted text as a Markdown string
+func (md *Markdown) ApplyFormatting(text string, formats ...string) string {
+    for i := len(formats) - 1; i >= 0; i-- {
+        switch formats[i] {
+        case "strikethrough":
+            text = "~~" + text + "~~"
+        case "bold":
+            text = "**" + text + "**"
+        case "italic":
+            text = "_" + text + "_"
+        case "underline":
+            text = "<u>" + text + "</u>"
+        case "subscript":
+            text = "<sub>" + text + "</sub>"
+        case "superscript":
+            text = "<sup>" + text + "</sup>"
+        case "code":
+            text = md.Code(text)
+        }
+    }
+    return text
+}
+
+// Code formats text as an inline code span. The backtick delimiter is longer
+// than any run of backticks inside text, and the content is padded with spaces
+// where CommonMark would otherwise strip or misread it. Empty text yields "",
+// since an empty code span cannot be written.
+//
+// Parameters:
+// - text: The text to format as code
+//
+// Returns:
+// - string: The inline code span, or "" if text is empty
+func (md *Markdown) Code(text string) string {
+    if text == "" {
+        return "" // "``" would merge with adjacent backticks
+    }
+    longest, run := 0, 0
+    for i := 0; i < len(text); i++ {
+        if text[i] != '`' {
+            run = 0
+            continue
+        }
+        run++
+        if run > longest {
+            longest = run
+        }
+    }
+    delimiter := strings.Repeat("`", longest+1)
+    if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") ||
+        (len(text) > 1 && text[0] == ' ' && text[len(text)-1] == ' ' && strings.TrimSpace(text) != "") {
+        text = " " + text + " "
+    }
+    return delimiter + text + delimiter
+}
+
+// Paragraph inserts a paragraph into the Markdown document with optional formatting.
+// Empty text is skipped, which strict mode records as an error.
+//
+// Parameters:
+// - text: The text content of the paragraph
+// - formats: Optional formatting, such as "bold" or "italic"
+func (md *Markdown) Paragraph(text string, formats ...string) {
+    if text == "" {
+        md.check(fmt.Errorf("markdown: paragraph text is empty"))
+        return // Skip empty paragraphs
+    }
+    if md.autoLinkURLs && md.flavor != GitHubMarkdown {
+        text = autoLinkURLs(text)
+    }
+    text = md.escapeText(text) // Autolinks are kept as they are
+    if md.smartTypography {
+        text = smartTypography(text)
+    }
+    formatted := md.ApplyFormatting(text, formats...)
+    md.write(wrapText(formatted, md.wrapWidth) + "\n\n")
+}
+
+// HTMLBlock inserts raw HTML verbatim, surrounded by blank lines so that
+// Markdown parsers treat it as an HTML block. The HTML is not escaped.
+//
+// Parameters:
+// - html: The HTML to insert
+func (md *Markdown) HTMLBlock(html string) {
+    html = strings.Trim(html, "\n")
+    if html == "" {
+        return // Skip empty HTML blocks
+    }
+    if md.needsBlankLine() {
+        html = "\n" + html // Separate the block from a preceding line
+    }
+    md.write(html + "\n\n")
+}
+
+// CodeBlock inserts a code block with optional syntax highlighting for a specified language.
+// Empty code is skipped, which strict mode records as an error.
+//
+// Parameters:
+// - language: The programming language for syntax highlighting (e.g., "go", "python")
+// - code: The code content to include in the block
+func (md *Markdown) CodeBlock(language, code string) {
+    if code == "" {
+        md.check(fmt.Errorf("markdown: code block is empty"))
+        return // Skip empty code blocks
+    }
+    md.FencedBlock(language, code)
+}
+
+// languageByExtension maps file extensions to the language names used in the
+// info string of fenced code blocks.
+var languageByExtension = map[string]string{
+    ".c": "c", ".h": "c", ".cpp": "cpp", ".cc": "cpp", ".hpp": "cpp", ".cs": "csharp",
+    ".css": "css", ".go": "go", ".html": "html", ".java": "java", ".js": "javascript",
+    ".json": "json", ".kt": "kotlin", ".md": "markdown", ".php": "php", ".py": "python",
+    ".rb": "ruby", ".rs": "rust", ".sh": "bash", ".sql": "sql", ".swift": "swift",
+    ".toml": "toml", ".ts": "typescript", ".xml": "xml", ".yaml": "yaml", ".yml": "yaml",
+}
+
+// CodeBlockFromFile inserts the content of a source file as a code block. When
+// no language is given, it is derived from the file extension.
+//
+// Parameters:
+// - path: The path of the file to embed
+// - language: The language for syntax highlighting, or "" to detect it
+//
+// Returns:
+// - error: The error encountered while reading the file, or nil
+func (md *Markdown) CodeBlockFromFile(path, language string) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+    code := strings.TrimRight(string(data), "\r\n")
+    if code == "" {
+        return fmt.Errorf("markdown: file %s is empty", path)
+    }
+    if language == "" {
+        language = languageByExtension[strings.ToLower(filepath.Ext(path))]
+    }
+    md.CodeBlock(language, code)
+    return nil
+}
+
+// Include appends the content of a Markdown file verbatim, separated from the
+// surrounding content by blank lines. Each file can be included only once, which
+// guards against accidental duplication when composing a document from partials.
+//
+// Parameters:
+// - path: The path of the Markdown file to include
+//
+// Returns:
+// - error: The error encountered while reading the file, or nil
+func (md *Markdown) Include(path string) error {
+    return md.IncludeWithOffset(path, 0)
+}
+
+// IncludeWithOffset works like Include but demotes the headings of the included
+// file by offset levels, so that a partial written with top-level headings can
+// be nested below a section. Levels are capped at 6.
+//
+// Parameters:
+// - path: The path of the Markdown file to include
+// - offset: The number of levels to demote headings by
+//
+// Returns:
+// - error: The error encountered while reading the file, or nil
+func (md *Markdown) IncludeWithOffset(path string, offset int) error {
+    if err := md.Err(); err != nil {
+        return err
+    }
+    abs, err := filepath.Abs(path)
+    if err != nil {
+        return err
+    }
+    md.lock()
+    done := md.included[abs]
+    md.unlock()
+    if done {
+        return fmt.Errorf("markdown: file %s is already included", path)
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+    md.lock()
+    if md.included == nil {
+        md.included = make(map[string]bool)
+    }
+    md.included[abs] = true
+    md.unlock()
+    content := strings.Trim(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+    if content == "" {
+        return nil // Nothing to include
+    }
+    if offset > 0 {
+        content = shiftHeadings(content, offset)
+    }
+    if md.needsBlankLine() {
+        content = "\n" + content // Separate the file from a preceding line
+    }
+    md.write(content + "\n\n")
+    return nil
+}
+
+// atxHeadingPattern matches the marker of an ATX heading.
+var atxHeadingPattern = regexp.MustCompile(`^(#{1,6})([ \t]|$)`)
+
+// shiftHeadings demotes the ATX headings in text, outside of fenced code
+// blocks, by offset levels. Levels are capped at 6.
+func shiftHeadings(text string, offset int) string {
+    lines := strings.Split(text, "\n")
+    var fences fenceState
+    for i, line := range lines {
+        if fences.scan(line) != proseLine {
+            continue
+        }
+        if m := atxHeadingPattern.FindStringSubmatch(line); m != nil {
+            level := clampLevel(len(m[1]) + offset)
+            lines[i] = strings.Repeat("#", level) + line[len(m[1]):]
+        }
+    }
+    return strings.Join(lines, "\n")
+}
+
+// SetSkipHiddenFiles controls whether FileTree leaves out files and directories
+// whose names start with a dot, such as .git.
+//
+// Parameters:
+// - enabled: Whether hidden files are skipped
+func (md *Markdown) SetSkipHiddenFiles(enabled bool) {
+    md.skipHiddenFiles = enabled
+}
+
+// FileTree inserts the directory tree below root as a code block drawn with
+// box-drawing characters. Entries are sorted by name and directories are marked
+// with a trailing slash.
+//
+// Parameters:
+// - root: The directory to render
+// - maxDepth: The number of levels below root to show, 0 for no limit
+//
+// Returns:
+// - error: The error encountered while reading the directories, or nil
+func (md *Markdown) FileTree(root string, maxDepth int) error {
+    if err := md.Err(); err != nil {
+        return err
+    }
+    tree, err := md.fileTreeNode(root, filepath.Base(filepath.Clean(root))+"/", 1, maxDepth)
+    if err != nil {
+        return err
+    }
+    md.FencedBlock("", renderTree(tree))
+    return nil
+}
+
+// fileTreeNode builds the tree of the directory dir, reading its entries if
+// depth does not exceed maxDepth.
+func (md *Markdown) fileTreeNode(dir, label string, depth, maxDepth int) (TreeNode, error) {
+    node := TreeNode{Label: label}
+    if maxDepth > 0 && depth > maxDepth {
+        return node, nil
+    }
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return node, err
+    }
+    for _, entry := range entries {
+        if md.skipHiddenFiles && strings.HasPrefix(entry.Name(), ".") {
+            continue
+        }
+        if !entry.IsDir() {
+            node.Children = append(node.Children, TreeNode{Label: entry.Name()})
+            continue
+        }
+        child, err := md.fileTreeNode(filepath.Join(dir, entry.Name()), entry.Name()+"/", depth+1, maxDepth)
+        if err != nil {
+            return node, err
+        }
+        node.Children = append(node.Children, child)
+    }
+    return node, nil
+}
+
+// TreeNode is a labelled node of a tree rendered by Tree.
+type TreeNode struct {
+    Label    string
+    Children []TreeNode
+}
+
+// Tree inserts a tree, such as a dependency graph or an org chart, as a code
+// block drawn with box-drawing characters.
+//
+// Parameters:
+// - root: The root node of the tree
+func (md *Markdown) Tree(root TreeNode) {
+    if root.Label == "" && len(root.Children) == 0 {
+        return // Skip empty trees
+    }
+    md.FencedBlock("", renderTree(root))
+}
+
+// renderTree draws root and its descendants with box-drawing characters, one
+// node per line.
+func renderTree(root TreeNode) string {
+    var b strings.Builder
+    b.WriteString(root.Label)
+    writeTreeChildren(&b, root.Children, "")
+    return b.String()
+}
+
+// writeTreeChildren draws children below a node whose lines start with prefix.
+func writeTreeChildren(b *strings.Builder, children []TreeNode, prefix string) {
+    for i, child := range children {
+        branch, indent := "├── ", "│   "
+        if i == len(children)-1 {
+            branch, indent = "└── ", "    "
+        }
+        b.WriteString("\n" + prefix + branch + child.Label)
+        writeTreeChildren(b, child.Children, prefix+indent)
+    }
+}
+
+// CodeBlockWithOptions inserts a code block whose info string highlights lines
+// and optionally enables line numbers, e.g. "go {1,3-4} {.line-numbers}", a
+// convention supported by many renderers.
+//
+// Parameters:
+// - language: The programming language for syntax highlighting (e.g., "go", "python")
+// - code: The code content to include in the block
+// - highlightLines: The 1-based line numbers to highlight
+// - showLineNumbers: Whether line numbers should be displayed
+func (md *Markdown) CodeBlockWithOptions(language, code string, highlightLines []int, showLineNumbers bool) {
+    if code == "" {
+        return // Skip empty code blocks
+    }
+    for _, line := range highlightLines {
+        if line < 1 {
+            md.check(fmt.Errorf("markdown: highlighted line %d is not positive", line))
+            return // Skip blocks with invalid line numbers
+        }
+    }
+    info := language
+    if ranges := lineRanges(highlightLines); ranges != "" {
+        info = strings.TrimSpace(info + " {" + ranges + "}")
+    }
+    if showLineNumbers {
+        info = strings.TrimSpace(info + " {.line-numbers}")
+    }
+    md.FencedBlock(info, code)
+}
+
+// lineRanges formats line numbers as a sorted, comma-separated list in which
+// consecutive lines are collapsed into ranges, e.g. "1,3-4".
+func lineRanges(lines []int) string {
+    sorted := append([]int(nil), lines...)
+    sort.Ints(sorted)
+    var parts []string
+    for i := 0; i < len(sorted); {
+        j := i
+        for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+            j++
+        }
+        if sorted[i] == sorted[j] {
+            parts = append(parts, strconv.Itoa(sorted[i]))
+        } else {
+            parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+        }
+        i = j + 1
+    }
+    return strings.Join(parts, ",")
+}
+
+// DiffBlock inserts a diff-highlighted code block. Removed lines are listed
+// first with a "-" prefix, followed by the added lines with a "+" prefix.
+//
+// Parameters:
+// - added: The lines that were added
+// - removed: The lines that were removed
+func (md *Markdown) DiffBlock(added, removed []string) {
+    if len(added) == 0 && len(removed) == 0 {
+        return // Skip empty diffs
+    }
+    lines := make([]string, 0, len(added)+len(removed))
+    for _, line := range removed {
+        lines = append(lines, "-"+line)
+    }
+    for _, line := range added {
+        lines = append(lines, "+"+line)
+    }
+    md.FencedBlock("diff", strings.Join(lines, "\n"))
+}
+
+// SetTildeFences selects "~~~" instead of "```" as the fence for code blocks.
+//
+// Parameters:
+// - enabled: Whether code blocks should be fenced with tildes
+func (md *Markdown) SetTildeFences(enabled bool) {
+    md.tildeFences = enabled
+}
+
+// FencedBlock inserts a fenced block with an arbitrary info string, e.g. for
+// formats such as "vega-lite" that have no dedicated method. The fence is one
+// character longer than the longest run of the fence character inside content,
+// and at least three characters long, so the content cannot close it.
+//
+// Parameters:
+// - info: The info string following the opening fence, e.g. a language name
+// - content: The content of the block
+func (md *Markdown) FencedBlock(info, content string) {
+    if content == "" {
+        return // Skip empty blocks
+    }
+    fence := md.fence(content)
+    md.write(fmt.Sprintf("%s%s\n%s\n%s\n\n", fence, info, content, fence))
+}
+
+// TextDiagram inserts a plain-text diagram, e.g. a box drawing, as a fenced
+// block without a language, so that it is shown in a monospace font with its
+// alignment intact and without syntax highlighting. Unlike CodeBlock it takes
+// the lines of the diagram, which are inserted exactly as given. The fence is
+// lengthened if the diagram contains fence characters.
+//
+// Parameters:
+// - lines: The lines of the diagram
+func (md *Markdown) TextDiagram(lines []string) {
+    if len(lines) == 0 {
+        return // Skip empty diagrams
+    }
+    md.FencedBlock("", strings.Join(lines, "\n"))
+}
+
+// fence returns a code fence that is safe to use around body.
+func (md *Markdown) fence(body string) string {
+    char := byte('`')
+    if md.tildeFences {
+        char = '~'
+    }
+    longest, run := 0, 0
+    for i := 0; i < len(body); i++ {
+        if body[i] != char {
+            run = 0
+            continue
+        }
+        run++
+        if run > longest {
+            longest = run
+        }
+    }
+    if longest < 3 {
+        longest = 2
+    }
+    return strings.Repeat(string(char), longest+1)
+}
+
+// ReferenceLink creates a Markdown reference link with a label, text, and URL.
+//
+// Parameters:
+// - label: The reference label
+// - text: The visible link text
+// - url: The destination URL
+func (md *Markdown) ReferenceLink(label, text, url string) {
+    if label == "" || text == "" || url == "" {
+        return // Skip invalid reference links
+    }
+    var b strings.Builder
+    b.WriteString(fmt.Sprintf("[%s]: %s\n", label, text))
+    b.WriteString(fmt.Sprintf("[%s](%s)\n\n", text, url))
+    md.write(b.String())
+}
+
+// Link creates an inline Markdown link. The URL is normalized so that spaces and
+// other disallowed characters are percent-encoded.
+//
+// Parameters:
+// - text: The visible link text
+// - url: The destination URL
+//
+// Returns:
+// - string: The Markdown link, or an empty string if the input is invalid
+func (md *Markdown) Link(text, url string) string {
+    link, err := md.LinkE(text, url)
+    md.check(err)
+    return link
+}
+
+// LinkE works like Link but returns an error describing why the link was rejected.
+//
+// Parameters:
+// - text: The visible link text
+// - url: The destination URL
+//
+// Returns:
+// - string: The Markdown link, or an empty string if the input is invalid
+// - error: A description of why the link was rejected, or nil
+func (md *Markdown) LinkE(text, url string) (string, error) {
+    if err := md.Err(); err != nil {
+        return "", err
+    }
+    if text == "" {
+        return "", fmt.Errorf("markdown: link text is empty")
+    }
+    target, err := md.resolveURL(url)
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("[%s](%s)", text, target), nil
+}
+
+// Link describes a link target with its visible text.
+type Link struct {
+    Text string
+    URL  string
+}
+
+// WikiPage starts a GitHub wiki page with its title heading and records links
+// for the wiki sidebar, which can be retrieved with WikiSidebar.
+//
+// Parameters:
+// - title: The title of the wiki page
+// - sidebarLinks: Links to add to the wiki sidebar
+func (md *Markdown) WikiPage(title string, sidebarLinks []Link) {
+    md.Heading(1, title, "", "")
+    md.lock()
+    md.wikiSidebar = append(md.wikiSidebar, sidebarLinks...)
+    md.unlock()
+}
+
+// WikiSidebar renders the content of the wiki's _Sidebar.md page from the links
+// recorded by WikiPage.
+//
+// Returns:
+// - string: The sidebar content as a bullet list of links
+func (md *Markdown) WikiSidebar() string {
+    md.lock()
+    links := append([]Link(nil), md.wikiSidebar...)
+    md.unlock()
+    var b strings.Builder
+    for _, l := range links {
+        if link := md.Link(l.Text, l.URL); link != "" {
+            b.WriteString("- " + link + "\n")
+        }
+    }
+    return b.String()
+}
+
+// MailtoLink creates an inline link that opens a new e-mail to the given address.
+//
+// Parameters:
+// - text: The visible link text
+// - email: The e-mail address, which must contain an "@"
+//
+// Returns:
+// - string: The Markdown link, or an empty string if the input is invalid
+func (md *Markdown) MailtoLink(text, email string) string {
+    if text == "" || !strings.Contains(email, "@") || strings.ContainsAny(email, " <>()") {
+        return ""
+    }
+    return fmt.Sprintf("[%s](mailto:%s)", text, email)
+}
+
+// TelLink creates an inline link that dials the given phone number.
+//
+// Parameters:
+// - text: The visible link text
+// - phone: The phone number, consisting of digits, "+", spaces, and dashes
+//
+// Returns:
+// - string: The Markdown link, or an empty string if the input is invalid
+func (md *Markdown) TelLink(text, phone string) string {
+    if text == "" || phone == "" {
+        return ""
+    }
+    digits := false
+    for _, r := range phone {
+        switch {
+        case r >= '0' && r <= '9':
+            digits = true
+        case r == '+' || r == ' ' || r == '-':
+        default:
+            return "" // Reject any other character
+        }
+    }
+    if !digits {
+        return ""
+    }
+    // Spaces are not allowed in link destinations, so use dashes instead
+    return fmt.Sprintf("[%s](tel:%s)", text, strings.ReplaceAll(phone, " ", "-"))
+}
+
+// Image inserts an image with alt text and a source URL.
+//
+// Parameters:
+// - altText: Alternative text for the image
+// - url: The image source URL
+func (md *Markdown) Image(altText, url string) {
+    md.check(md.ImageE(altText, url))
+}
+
+// ImageE works like Image but returns an error instead of silently skipping
+// invalid input.
+//
+// Parameters:
+// - altText: Alternative text for the image
+// - url: The image source URL
+//
+// Returns:
+// - error: A description of why the image was rejected, or nil
+func (md *Markdown) ImageE(altText, url string) error {
+    if err := md.Err(); err != nil {
+        return err
+    }
+    if altText == "" {
+        return fmt.Errorf("markdown: image alt text is empty")
+    }
+    source, err := md.resolveURL(url)
+    if err != nil {
+        return err
+    }
+    md.write(fmt.Sprintf("![%s](%s)\n\n", altText, source))
+    return nil
+}
+
+// Badge describes a shield-style badge: an image that optionally links somewhere.
+type Badge struct {
+    AltText  string
+    ImageURL string
+    LinkURL  string
+}
+
+// Badge inserts a badge, e.g. from shields.io, as a linked image.
+//
+// Parameters:
+// - altText: Alternative text for the badge image
+// - imageURL: The badge image URL
+// - linkURL: The URL the badge links to; without it the image is not linked
+func (md *Markdown) Badge(altText, imageURL, linkURL string) {
+    md.BadgeRow(Badge{AltText: altText, ImageURL: imageURL, LinkURL: linkURL})
+}
+
+// BadgeRow inserts several badges on one line, separated by spaces. Badges
+// without an image URL are skipped.
+//
+// Parameters:
+// - badges: The badges in display order
+func (md *Markdown) BadgeRow(badges ...Badge) {
+    var row []string
+    for _, b := range badges {
+        if badge := md.badge(b); badge != "" {
+            row = append(row, badge)
+        }
+    }
+    if len(row) == 0 {
+        return // Skip empty badge rows
+    }
+    md.write(strings.Join(row, " ") + "\n\n")
+}
+
+// badge renders a single badge, or an empty string if it has no valid image.
+func (md *Markdown) badge(b Badge) string {
+    image, err := md.resolveURL(b.ImageURL)
+    if err != nil {
+        return ""
+    }
+    badge := fmt.Sprintf("![%s](%s)", b.AltText, image)
+    if link, err := md.resolveURL(b.LinkURL); err == nil {
+        badge = fmt.Sprintf("[%s](%s)", badge, link)
+    }
+    return badge
+}
+
+// Figure inserts an image with an optional caption as an HTML figure block,
+// since Markdown has no native figure syntax. Captioned figures get the IDs
+// "figure-1", "figure-2", and so on, and are listed by ListOfFigures.
+//
+// Parameters:
+// - altText: Alternative text for the image
+// - url: The image source URL
+// - caption: The caption shown below the image; omitted when empty
+func (md *Markdown) Figure(altText, url, caption string) {
+    if url == "" {
+        return // Skip figures without an image source
+    }
+    figure := fmt.Sprintf("<figure><img src=\"%s\" alt=\"%s\">", html.EscapeString(url), html.EscapeString(altText))
+    if caption != "" {
+        id := md.trackCaption(&md.figures, "figure", caption)
+        figure = fmt.Sprintf("<figure id=\"%s\">", id) + strings.TrimPrefix(figure, "<figure>")
+        figure += fmt.Sprintf("<figcaption>%s</figcaption>", html.EscapeString(caption))
+    }
+    md.write(figure + "</figure>\n\n")
+}
+
+// trackCaption records a captioned figure or table in list and returns its ID,
+// formed from prefix and its position in the list.
+func (md *Markdown) trackCaption(list *[]captioned, prefix, caption string) string {
+    md.lock()
+    defer md.unlock()
+    id := fmt.Sprintf("%s-%d", prefix, len(*list)+1)
+    *list = append(*list, captioned{id: id, caption: caption})
+    return id
+}
+
+// ListOfFigures inserts a numbered list of the captioned figures added so far,
+// each linking to its figure.
+func (md *Markdown) ListOfFigures() {
+    md.lock()
+    figures := append([]captioned(nil), md.figures...)
+    md.unlock()
+    md.writeCaptionList(figures)
+}
+
+// ListOfTables inserts a numbered list of the captioned tables added so far,
+// each linking to its table.
+func (md *Markdown) ListOfTables() {
+    md.lock()
+    tables := append([]captioned(nil), md.tables...)
+    md.unlock()
+    md.writeCaptionList(tables)
+}
+
+// writeCaptionList writes entries as an ordered list of links.
+func (md *Markdown) writeCaptionList(entries []captioned) {
+    if len(entries) == 0 {
+        return // Skip empty lists
+    }
+    var b strings.Builder
+    for i, e := range entries {
+        b.WriteString(fmt.Sprintf("%d. [%s](#%s)\n", i+1, e.caption, e.id))
+    }
+    md.write(b.String() + "\n")
+}
+
+// ImageSpec describes an image shown by Gallery. Link is optional and makes the
+// image a link.
+type ImageSpec struct {
+    Alt  string
+    URL  string
+    Link string
+}
+
+// Gallery arranges images in a grid with the given number of columns, e.g. for
+// screenshots. In HTML output mode a CSS grid is emitted; otherwise the images
+// are placed in a table without header text. Images without a URL are skipped.
+//
+// Parameters:
+// - images: The images in display order
+// - columns: The number of images per row, at least 1
+func (md *Markdown) Gallery(images []ImageSpec, columns int) {
+    if columns < 1 {
+        columns = 1
+    }
+    var valid []ImageSpec
+    for _, img := range images {
+        if img.URL != "" {
+            valid = append(valid, img)
+        }
+    }
+    if len(valid) == 0 {
+        return // Skip empty galleries
+    }
+    if len(valid) < columns {
+        columns = len(valid)
+    }
+    if md.htmlOutput {
+        var b strings.Builder
+        b.WriteString(fmt.Sprintf("<div style=\"display: grid; grid-template-columns: repeat(%d, 1fr); gap: 1em;\">\n", columns))
+        for _, img := range valid {
+            tag := fmt.Sprintf("<img src=\"%s\" alt=\"%s\">", html.EscapeString(img.URL), html.EscapeString(img.Alt))
+            if img.Link != "" {
+                tag = fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(img.Link), tag)
+            }
+            b.WriteString(tag + "\n")
+        }
+        md.write(b.String() + "</div>\n\n")
+        return
+    }
+    headers := make([]string, columns)
+    align := make([]string, columns)
+    for i := range align {
+        align[i] = "center"
+    }
+    var rows [][]string
+    for i, img := range valid {
+        if i%columns == 0 {
+            rows = append(rows, make([]string, columns))
+        }
+        cell := fmt.Sprintf("![%s](%s)", img.Alt, img.URL)
+        if img.Link != "" {
+            cell = fmt.Sprintf("[%s](%s)", cell, img.Link)
+        }
+        rows[len(rows)-1][i%columns] = cell
+    }
+    md.writeTable(headers, rows, align)
+}
+
+// List generates a Markdown list (ordered or unordered). A list without items
+// is skipped, which strict mode records as an error.
+//
+// Parameters:
+// - items: A slice of strings representing each list item
+// - isOrdered: If true, creates an ordered list; otherwise, an unordered list
+func (md *Markdown) List(items []string, isOrdered bool) {
+    if len(items) == 0 {
+        md.check(fmt.Errorf("markdown: list has no items"))
+        return // Skip empty lists
+    }
+    var b strings.Builder
+    for i, item := range items {
+        item = md.escapeText(item)
+        if isOrdered {
+            b.WriteString(fmt.Sprintf("%d. %s\n", i+1, item))
+        } else {
+            b.WriteString(fmt.Sprintf("- %s\n", item))
+        }
+    }
+    b.WriteString("\n")
+    md.write(b.String())
+}
+
+// orderedListTypes maps the styles of OrderedListStyle to the type attribute of
+// HTML ordered lists.
+var orderedListTypes = map[string]string{
+    "decimal":     "1",
+    "lower-alpha": "a",
+    "upper-alpha": "A",
+    "lower-roman": "i",
+    "upper-roman": "I",
+}
+
+// OrderedListStyle creates an ordered list numbered in the given style. Since
+// Markdown lists only support decimal numbers, other styles are rendered as an
+// HTML list in HTML output mode and otherwise as lines starting with the literal
+// marker, e.g. "iv. ", separated by hard line breaks. Unknown styles fall back
+// to decimal numbers and are recorded as an error in strict mode.
+//
+// Parameters:
+// - items: The list items
+// - style: "decimal", "lower-alpha", "upper-alpha", "lower-roman", or "upper-roman"
+func (md *Markdown) OrderedListStyle(items []string, style string) {
+    if len(items) == 0 {
+        return // Skip empty lists
+    }
+    listType, ok := orderedListTypes[style]
+    if !ok {
+        md.check(fmt.Errorf("markdown: unknown list style %q", style))
+        listType = "1"
+    }
+    if listType == "1" {
+        md.List(items, true)
+        return
+    }
+    var b strings.Builder
+    if md.htmlOutput {
+        b.WriteString(fmt.Sprintf("<ol type=\"%s\">\n", listType))
+        for _, item := range items {
+            b.WriteString("<li>" + html.EscapeString(item) + "</li>\n")
+        }
+        md.write(b.String() + "</ol>\n\n")
+        return
+    }
+    for i, item := range items {
+        if i > 0 {
+            b.WriteString("\\\n") // Hard line break
+        }
+        b.WriteString(listMarker(i+1, listType) + ". " + md.escapeText(item))
+    }
+    md.write(b.String() + "\n\n")
+}
+
+// listMarker returns the marker of the n-th item of an ordered list whose HTML
+// type is listType.
+func listMarker(n int, listType string) string {
+    switch listType {
+    case "a", "A":
+        marker := ""
+        for ; n > 0; n = (n - 1) / 26 {
+            marker = string(rune('a'+(n-1)%26)) + marker
+        }
+        if listType == "A" {
+            return strings.ToUpper(marker)
+        }
+        return marker
+    case "i", "I":
+        values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+        numerals := []string{"m", "cm", "d", "cd", "c", "xc", "l", "xl", "x", "ix", "v", "iv", "i"}
+        var marker strings.Builder
+        for i, v := range values {
+            for ; n >= v; n -= v {
+                marker.WriteString(numerals[i])
+            }
+        }
+        if listType == "I" {
+            return strings.ToUpper(marker.String())
+        }
+        return marker.String()
+    }
+    return strconv.Itoa(n)
+}
+
+// ListItemWithBody inserts a list item followed by content that belongs to the
+// item, such as further paragraphs or code blocks. The content added by body is
+// indented to align with the item text. Consecutive calls with the same kind of
+// marker form a single list. Tables are best added with HTMLTable, since pipe
+// tables inside list items are not supported by all renderers.
+//
+// Parameters:
+// - marker: The list marker, e.g. "-" or "1."
+// - text: The text of the item
+// - body: A function that adds the content of the item, or nil
+func (md *Markdown) ListItemWithBody(marker, text string, body func(*Markdown)) {
+    if marker == "" || text == "" {
+        return // Skip items without marker or text
+    }
+    item := marker + " " + md.escapeText(text) + "\n\n"
+    if body != nil {
+        if content := md.render(body); content != "" {
+            indent := strings.Repeat(" ", utf8.RuneCountInString(marker)+1)
+            item += prefixLines(content, indent) + "\n\n"
+        }
+    }
+    md.write(item)
+}
+
+// NestedList creates a nested list in Markdown format.
+//
+// Parameters:
+// - nestedItems: A 2D slice of strings, where each sub-slice represents a nested list
+// - isOrdered: If true, creates an ordered nested list; otherwise, unordered
+func (md *Markdown) NestedList(nestedItems [][]string, isOrdered bool) {
+    if len(nestedItems) == 0 {
+        return // Skip empty nested lists
+    }
+    var b strings.Builder
+    for i, items := range nestedItems {
+        if isOrdered {
+            for _, item := range items {
+                b.WriteString(fmt.Sprintf("%d. %s\n", i+1, md.escapeText(item)))
+            }
+        } else {
+            for j, item := range items {
+                item = md.escapeText(item)
+                if j == 0 {
+                    b.WriteString(fmt.Sprintf("- %s\n", item)) // First item
+                } else {
+                    b.WriteString(fmt.Sprintf("  - %s\n", item)) // Nested items
+                }
+            }
+        }
+    }
+    b.WriteString("\n")
+    md.write(b.String())
 }
 
 // Table creates a Markdown table with headers, rows, and optional alignment.
+// Rows whose cell count does not match the headers are skipped, except in strict
+// mode where the table is rejected as by TableE.
+//
+// Parameters:
+// - headers: A slice of strings for the table headers
+// - rows: A 2D slice representing rows in the table
+// - align: A slice for alignment settings ("left", "center", or "right") for each column
+func (md *Markdown) Table(headers []string, rows [][]string, align []string) {
+    headers, rows = md.escapeCells(headers, rows)
+    md.table(headers, rows, align)
+}
+
+// table works like Table for cells that have already been auto-escaped.
+func (md *Markdown) table(headers []string, rows [][]string, align []string) {
+    if md.strict {
+        md.check(md.tableE(headers, rows, align))
+        return
+    }
+    if len(headers) == 0 || len(rows) == 0 {
+        return // Skip empty tables
+    }
+    md.writeTable(headers, rows, align)
+}
+
+// TableE works like Table but rejects the whole table with an error if it is
+// empty or any row does not match the header count.
+//
+// Parameters:
+// - headers: A slice of strings for the table headers
+// - rows: A 2D slice representing rows in the table
+// - align: A slice for alignment settings ("left", "center", or "right") for each column
+//
+// Returns:
+// - error: A description of why the table was rejected, or nil
+func (md *Markdown) TableE(headers []string, rows [][]string, align []string) error {
+    headers, rows = md.escapeCells(headers, rows)
+    return md.tableE(headers, rows, align)
+}
+
+// tableE works like TableE for cells that have already been auto-escaped.
+func (md *Markdown) tableE(headers []string, rows [][]string, align []string) error {
+    if err := md.Err(); err != nil {
+        return err
+    }
+    if err := validateTable(headers, rows); err != nil {
+        return err
+    }
+    md.writeTable(headers, rows, align)
+    return nil
+}
+
+// writeTable renders a pipe table, skipping rows that do not match the headers.
+// Cells are escaped with EscapeTableCell.
+func (md *Markdown) writeTable(headers []string, rows [][]string, align []string) {
+    headers, rows = mapCells(headers, rows, md.EscapeTableCell)
+    if md.alignedTables {
+        md.writeAlignedTable(headers, rows, align)
+        return
+    }
+    var b strings.Builder
+    headerLine := "| " + strings.Join(headers, " | ") + " |\n"
+    alignment := "|"
+    for i := range headers {
+        a := "" // Columns without an alignment use the default
+        if i < len(align) {
+            a = align[i]
+        }
+        switch a {
+        case "left":
+            alignment += ":---|"
+        case "center":
+            alignment += ":---:|"
+        case "right":
+            alignment += "---:|"
+        default:
+            alignment += "---|"
+        }
+    }
+    b.WriteString(headerLine + alignment + "\n")
+    for _, row := range rows {
+        if len(row) != len(headers) {
+            continue // Ensure rows match header count
+        }
+        b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+    }
+    b.WriteString("\n")
+    md.write(b.String())
+}
+
+// SetAlignedTables controls whether Table pads cells with spaces so that the
+// columns line up in the Markdown source, which makes tables easier to read
+// and edit as plain text. Widths are measured with DisplayWidth, so cells with
+// wide characters such as CJK ideographs line up as well.
+//
+// Parameters:
+// - enabled: Whether table columns should be aligned
+func (md *Markdown) SetAlignedTables(enabled bool) {
+    md.alignedTables = enabled
+}
+
+// writeAlignedTable renders a pipe table whose columns are padded to equal
+// widths, skipping rows that do not match the headers.
+func (md *Markdown) writeAlignedTable(headers []string, rows [][]string, align []string) {
+    widths := make([]int, len(headers))
+    for i, header := range headers {
+        widths[i] = DisplayWidth(header)
+    }
+    var kept [][]string
+    for _, row := range rows {
+        if len(row) != len(headers) {
+            continue // Ensure rows match header count
+        }
+        kept = append(kept, row)
+        for i, cell := range row {
+            if w := DisplayWidth(cell); w > widths[i] {
+                widths[i] = w
+            }
+        }
+    }
+    aligns := make([]string, len(headers))
+    separators := make([]string, len(headers))
+    for i := range headers {
+        if i < len(align) {
+            aligns[i] = align[i]
+        }
+        if widths[i] < 3 {
+            widths[i] = 3 // Room for the separator dashes
+        }
+        switch aligns[i] {
+        case "left":
+            separators[i] = ":" + strings.Repeat("-", widths[i]-1)
+        case "center":
+            separators[i] = ":" + strings.Repeat("-", widths[i]-2) + ":"
+        case "right":
+            separators[i] = strings.Repeat("-", widths[i]-1) + ":"
+        default:
+            separators[i] = strings.Repeat("-", widths[i])
+        }
+    }
+    line := func(cells []string) string {
+        padded := make([]string, len(cells))
+        for i, cell := range cells {
+            padded[i] = padCell(cell, widths[i], aligns[i])
+        }
+        return "| " + strings.Join(padded, " | ") + " |\n"
+    }
+    var b strings.Builder
+    b.WriteString(line(headers))
+    b.WriteString("| " + strings.Join(separators, " | ") + " |\n")
+    for _, row := range kept {
+        b.WriteString(line(row))
+    }
+    b.WriteString("\n")
+    md.write(b.String())
+}
+
+// TableBuilder assembles a table row by row and renders it either as a pipe
+// table or as an HTML table.
+type TableBuilder struct {
+    md      *Markdown
+    headers []string
+    rows    [][]string
+    align   []string
+    footer  []string
+    caption string
+}
+
+// NewTable starts a table with the given headers.
+//
+// Parameters:
+// - headers: The column headers
+//
+// Returns:
+// - *TableBuilder: A builder for adding rows, alignment, and a footer
+func (md *Markdown) NewTable(headers ...string) *TableBuilder {
+    return &TableBuilder{md: md, headers: headers}
+}
+
+// AddRow appends a body row to the table.
+//
+// Parameters:
+// - cells: The cells of the row
+//
+// Returns:
+// - *TableBuilder: The builder itself, to allow chaining
+func (t *TableBuilder) AddRow(cells ...string) *TableBuilder {
+    t.rows = append(t.rows, cells)
+    return t
+}
+
+// SetAlign sets the alignment ("left", "center", or "right") of each column.
+//
+// Parameters:
+// - align: The alignment per column
+//
+// Returns:
+// - *TableBuilder: The builder itself, to allow chaining
+func (t *TableBuilder) SetAlign(align ...string) *TableBuilder {
+    t.align = align
+    return t
+}
+
+// SetFooter sets a summary row, e.g. for totals. It is padded with empty cells
+// or truncated to the number of headers.
+//
+// Parameters:
+// - cells: The cells of the footer row
+//
+// Returns:
+// - *TableBuilder: The builder itself, to allow chaining
+func (t *TableBuilder) SetFooter(cells ...string) *TableBuilder {
+    footer := make([]string, len(t.headers))
+    copy(footer, cells)
+    t.footer = footer
+    return t
+}
+
+// SetCaption sets a caption for the table. Captioned tables get the IDs
+// "table-1", "table-2", and so on, and are listed by ListOfTables.
+//
+// Parameters:
+// - caption: The caption of the table
+//
+// Returns:
+// - *TableBuilder: The builder itself, to allow chaining
+func (t *TableBuilder) SetCaption(caption string) *TableBuilder {
+    t.caption = caption
+    return t
+}
+
+// Render writes the table as a pipe table. Since pipe tables have no footer
+// section, the footer is rendered as a final row with bold cells. A caption is
+// written as a bold paragraph with an anchor above the table.
+func (t *TableBuilder) Render() {
+    headers, rows := t.md.escapeCells(t.headers, t.rows) // Before the footer gets its markup
+    if t.footer != nil {
+        footer := make([]string, len(t.footer))
+        for i, cell := range t.footer {
+            if cell != "" {
+                footer[i] = "**" + t.md.escapeText(cell) + "**"
+            }
+        }
+        rows = append(append([][]string(nil), rows...), footer)
+    }
+    if t.caption == "" {
+        t.md.table(headers, rows, t.align)
+        return
+    }
+    table := t.md.render(func(sub *Markdown) {
+        sub.table(headers, rows, t.align)
+    })
+    if table == "" {
+        return // Skip empty tables
+    }
+    id := t.md.trackCaption(&t.md.tables, "table", t.caption)
+    t.md.write(fmt.Sprintf("<a id=\"%s\"></a>**%s**\n\n%s\n\n", id, t.caption, table))
+}
+
+// RenderHTML writes the table as an HTML table with the footer in <tfoot> and
+// the caption in <caption>.
+func (t *TableBuilder) RenderHTML() {
+    rows := make([][]TableCell, 0, len(t.rows))
+    for _, row := range t.rows {
+        rows = append(rows, tableCells(row))
+    }
+    if len(t.headers) == 0 && len(rows) == 0 {
+        return // Skip empty tables
+    }
+    table := htmlTable(tableCells(t.headers), rows, tableCells(t.footer), html.EscapeString)
+    if t.caption != "" {
+        id := t.md.trackCaption(&t.md.tables, "table", t.caption)
+        table = fmt.Sprintf("<table id=\"%s\">\n<caption>%s</caption>\n", id, html.EscapeString(t.caption)) + strings.TrimPrefix(table, "<table>\n")
+    }
+    t.md.write(table)
+}
+
+// tableCells converts plain cell texts into table cells without spans.
+func tableCells(texts []string) []TableCell {
+    cells := make([]TableCell, 0, len(texts))
+    for _, text := range texts {
+        cells = append(cells, TableCell{Text: text})
+    }
+    return cells
+}
+
+// TableCell is a cell of an HTML table. ColSpan and RowSpan are only rendered
+// when greater than one.
+type TableCell struct {
+    Text    string
+    ColSpan int
+    RowSpan int
+}
+
+// HTMLTable creates an HTML table, which unlike a pipe table supports cells
+// spanning several columns or rows. Cell text is HTML-escaped.
+//
+// Parameters:
+// - headers: The cells of the header row
+// - rows: The cells of each body row
+func (md *Markdown) HTMLTable(headers []TableCell, rows [][]TableCell) {
+    if len(headers) == 0 && len(rows) == 0 {
+        return // Skip empty tables
+    }
+    md.write(htmlTable(headers, rows, nil, html.EscapeString))
+}
+
+// htmlTable renders an HTML table with optional header, body, and footer rows.
+// escape converts the text of each cell to HTML.
+func htmlTable(headers []TableCell, rows [][]TableCell, footer []TableCell, escape func(string) string) string {
+    var b strings.Builder
+    b.WriteString("<table>\n")
+    if len(headers) > 0 {
+        b.WriteString("<thead>\n" + htmlTableRow("th", headers, escape) + "</thead>\n")
+    }
+    if len(rows) > 0 {
+        b.WriteString("<tbody>\n")
+        for _, row := range rows {
+            b.WriteString(htmlTableRow("td", row, escape))
+        }
+        b.WriteString("</tbody>\n")
+    }
+    if len(footer) > 0 {
+        b.WriteString("<tfoot>\n" + htmlTableRow("td", footer, escape) + "</tfoot>\n")
+    }
+    b.WriteString("</table>\n\n")
+    return b.String()
+}
+
+// htmlTableRow renders a table row whose cells use the given tag (th or td),
+// converting the cell text to HTML with escape.
+func htmlTableRow(tag string, cells []TableCell, escape func(string) string) string {
+    var b strings.Builder
+    b.WriteString("<tr>")
+    for _, cell := range cells {
+        b.WriteString("<" + tag)
+        if cell.ColSpan > 1 {
+            b.WriteString(fmt.Sprintf(" colspan=\"%d\"", cell.ColSpan))
+        }
+        if cell.RowSpan > 1 {
+            b.WriteString(fmt.Sprintf(" rowspan=\"%d\"", cell.RowSpan))
+        }
+        b.WriteString(">" + escape(cell.Text) + "</" + tag + ">")
+    }
+    b.WriteString("</tr>\n")
+    return b.String()
+}
+
+// TruthTable renders the truth table of a boolean function. All combinations of
+// the input variables are enumerated in binary order, starting with all inputs
+// false, and fn is evaluated for each of them. Values are shown as T and F.
+//
+// Parameters:
+// - variables: The names of the input variables
+// - fn: The function to evaluate, receiving one value per variable
+func (md *Markdown) TruthTable(variables []string, fn func([]bool) bool) {
+    if len(variables) == 0 || fn == nil {
+        return // Skip tables without inputs or function
+    }
+    headers := append(append([]string(nil), variables...), "Result")
+    align := make([]string, len(headers))
+    for i := range align {
+        align[i] = "center"
+    }
+    var rows [][]string
+    for combination := 0; combination < 1<<len(variables); combination++ {
+        inputs := make([]bool, len(variables))
+        row := make([]string, 0, len(headers))
+        for i := range variables {
+            inputs[i] = combination&(1<<(len(variables)-1-i)) != 0
+            row = append(row, truthValue(inputs[i]))
+        }
+        rows = append(rows, append(row, truthValue(fn(inputs))))
+    }
+    md.Table(headers, rows, align)
+}
+
+// truthValue formats a boolean as T or F.
+func truthValue(b bool) string {
+    if b {
+        return "T"
+    }
+    return "F"
+}
+
+// Conversion describes a unit conversion: one From equals Factor To.
+type Conversion struct {
+    From   string
+    To     string
+    Factor float64
+}
+
+// ConversionTable renders a unit-conversion reference table with a bold title.
+// Factors are formatted with the precision set by SetPrecision.
+//
+// Parameters:
+// - title: The title shown above the table; omitted when empty
+// - rows: The conversions to list in the given order
+func (md *Markdown) ConversionTable(title string, rows []Conversion) {
+    if len(rows) == 0 {
+        return // Skip empty conversion tables
+    }
+    cells := make([][]string, 0, len(rows))
+    for _, c := range rows {
+        cells = append(cells, []string{c.From, c.To, md.formatNumber(c.Factor)})
+    }
+    if title != "" {
+        md.write("**" + title + "**\n\n")
+    }
+    md.Table([]string{"From", "To", "Factor"}, cells, []string{"left", "left", "right"})
+}
+
+// Blockquote inserts a blockquote into the Markdown content. Empty text is
+// skipped, which strict mode records as an error.
+//
+// Parameters:
+// - text: The text for the blockquote
+func (md *Markdown) Blockquote(text string) {
+    if text == "" {
+        md.check(fmt.Errorf("markdown: blockquote text is empty"))
+        return // Skip empty blockquotes
+    }
+    if md.wrapWidth > 0 {
+        md.write(prefixLines(wrapText(text, md.wrapWidth-2), "> ") + "\n\n")
+        return
+    }
+    md.write("> " + text + "\n\n")
+}
+
+// WithWrapWidth makes Paragraph and Blockquote wrap their text at word
+// boundaries so that lines do not exceed the given number of columns, which
+// keeps diffs of source-controlled documents small. Inline code and links are
+// never split, so a line may exceed the width if a single word is too long.
+//
+// Parameters:
+// - width: The maximum line width in columns, 0 to disable wrapping
+//
+// Returns:
+// - *Markdown: The document itself, to allow chaining
+func (md *Markdown) WithWrapWidth(width int) *Markdown {
+    if width < 0 {
+        width = 0
+    }
+    md.wrapWidth = width
+    return md
+}
+
+// WithSmartTypography makes Paragraph replace straight double quotes with
+// curly quotes, apostrophes with ’, "--" with an en dash, and "---" with
+// an em dash. Code spans, code blocks, and link targets are left untouched.
+//
+// Parameters:
+// - enabled: Whether typographic replacements are applied
+//
+// Returns:
+// - *Markdown: The document itself, to allow chaining
+func (md *Markdown) WithSmartTypography(enabled bool) *Markdown {
+    md.smartTypography = enabled
+    return md
+}
+
+// WithAutoLinkURLs makes Paragraph wrap bare http and https URLs in angle
+// brackets, turning them into autolinks for flavors that do not link URLs
+// automatically. GitHub-flavored Markdown links them by itself, so the option
+// has no effect there. URLs in code, links, and HTML tags are left alone.
+//
+// Parameters:
+// - enabled: Whether bare URLs are turned into autolinks
+//
+// Returns:
+// - *Markdown: The document itself, to allow chaining
+func (md *Markdown) WithAutoLinkURLs(enabled bool) *Markdown {
+    md.autoLinkURLs = enabled
+    return md
+}
+
+var (
+    // bareURLPattern matches http and https URLs in text.
+    bareURLPattern = regexp.MustCompile(`https?://[^\s<>]+`)
+    // linkSyntaxPattern matches inline links, images, autolinks, and HTML tags.
+    linkSyntaxPattern = regexp.MustCompile(`!?\[[^\[\]]*\]\([^()\s]*\)|<[^<>]+>`)
+)
+
+// autoLinkURLs wraps the bare URLs in text in angle brackets.
+func autoLinkURLs(text string) string {
+    wrap := func(prose string) string {
+        return bareURLPattern.ReplaceAllStringFunc(prose, func(u string) string {
+            trimmed := strings.TrimRight(u, ".,;:!?'\"")
+            for strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+                trimmed = strings.TrimSuffix(trimmed, ")") // Closing parenthesis of the sentence
+            }
+            return "<" + trimmed + ">" + u[len(trimmed):]
+        })
+    }
+    return mapOutsideCode(text, func(prose string) string {
+        var out strings.Builder
+        start := 0
+        for _, loc := range linkSyntaxPattern.FindAllStringIndex(prose, -1) {
+            out.WriteString(wrap(prose[start:loc[0]]))
+            out.WriteString(prose[loc[0]:loc[1]])
+            start = loc[1]
+        }
+        out.WriteString(wrap(prose[start:]))
+        return out.String()
+    })
+}
+
+// linkTargetPattern matches link destinations, autolinks, and HTML tags, which
+// must not be altered by text transformations.
+var linkTargetPattern = regexp.MustCompile(`\]\([^()\s]*\)|<[a-zA-Z/!][^<>]*>`)
+
+// smartTypography applies the replacements of WithSmartTypography to text.
+func smartTypography(text string) string {
+    return mapOutsideCode(text, func(prose string) string {
+        var out strings.Builder
+        start := 0
+        for _, loc := range linkTargetPattern.FindAllStringIndex(prose, -1) {
+            out.WriteString(smartPunctuation(prose[start:loc[0]], out.String()))
+            out.WriteString(prose[loc[0]:loc[1]])
+            start = loc[1]
+        }
+        out.WriteString(smartPunctuation(prose[start:], out.String()))
+        return out.String()
+    })
+}
+
+// smartPunctuation replaces quotes and dashes in text. before is the text that
+// precedes it, used to decide whether a leading quote opens or closes.
+func smartPunctuation(text, before string) string {
+    text = strings.ReplaceAll(text, "---", "—")
+    text = strings.ReplaceAll(text, "--", "–")
+    text = strings.ReplaceAll(text, "'", "’")
+    var out strings.Builder
+    prev, _ := utf8.DecodeLastRuneInString(before)
+    if before == "" {
+        prev = ' '
+    }
+    for _, r := range text {
+        if r == '"' {
+            if unicode.IsSpace(prev) || strings.ContainsRune("([{–—", prev) {
+                r = '“'
+            } else {
+                r = '”'
+            }
+        }
+        out.WriteRune(r)
+        prev = r
+    }
+    return out.String()
+}
+
+// wrapLinkPattern matches an inline link or image target at the start of text.
+var wrapLinkPattern = regexp.MustCompile(`^\[[^\[\]]*\]\([^()\s]*\)`)
+
+// blockMarkerPattern matches words that would turn a wrapped line into another
+// block if they started it, such as list markers, heading markers, quote
+// markers, and rules.
+var blockMarkerPattern = regexp.MustCompile(`^(#{1,6}|[-+*=]+|_{3,}|\d{1,9}[.)]|>.*)$`)
+
+// wrapText wraps each line of text at width columns. Existing line breaks are
+// kept. A width of zero or less returns text unchanged. A line never starts
+// with a word that would make it another block, such as "-" or "1."; the
+// previous word moves along, or the line runs over if that is not possible.
+func wrapText(text string, width int) string {
+    if width <= 0 {
+        return text
+    }
+    lines := strings.Split(text, "\n")
+    for i, line := range lines {
+        var wrapped, current []string
+        col := 0
+        for _, word := range wrapWords(line) {
+            n := DisplayWidth(word)
+            if col > 0 && col+1+n > width {
+                last := current[len(current)-1]
+                switch {
+                case !blockMarkerPattern.MatchString(word):
+                    wrapped = append(wrapped, strings.Join(current, " "))
+                    current = nil
+                case len(current) > 1 && !blockMarkerPattern.MatchString(last):
+                    wrapped = append(wrapped, strings.Join(current[:len(current)-1], " "))
+                    current = []string{last} // Carry the previous word over
+                }
+            }
+            current = append(current, word)
+            col = DisplayWidth(strings.Join(current, " "))
+        }
+        lines[i] = strings.Join(append(wrapped, strings.Join(current, " ")), "\n")
+    }
+    return strings.Join(lines, "\n")
+}
+
+// wrapWords splits line into words at spaces, keeping inline code spans and
+// links in one piece.
+func wrapWords(line string) []string {
+    var words []string
+    var word strings.Builder
+    for i := 0; i < len(line); {
+        switch {
+        case line[i] == ' ' || line[i] == '\t':
+            if word.Len() > 0 {
+                words = append(words, word.String())
+                word.Reset()
+            }
+            i++
+            continue
+        case line[i] == '`':
+            n := 0
+            for i+n < len(line) && line[i+n] == '`' {
+                n++
+            }
+            if end := closingBackticks(line, i+n, n); end >= 0 {
+                word.WriteString(line[i : end+n])
+                i = end + n
+                continue
+            }
+            word.WriteString(line[i : i+n]) // Unmatched backticks are literal text
+            i += n
+            continue
+        case line[i] == '[':
+            if m := wrapLinkPattern.FindString(line[i:]); m != "" {
+                word.WriteString(m)
+                i += len(m)
+                continue
+            }
+        }
+        word.WriteByte(line[i])
+        i++
+    }
+    if word.Len() > 0 {
+        words = append(words, word.String())
+    }
+    return words
+}
+
+// IfFlavor runs fn only when the document uses the given flavor, so content for
+// a specific target can be added without checking the flavor at call sites.
+// fn adds its content to md directly.
+//
+// Parameters:
+// - flavor: The flavor the content is meant for
+// - fn: A function that adds the content to the document
+func (md *Markdown) IfFlavor(flavor int, fn func(*Markdown)) {
+    if fn == nil || md.flavor != flavor {
+        return
+    }
+    fn(md)
+}
+
+// Repeat calls fn n times with the indexes 0 to n-1, e.g. to generate a number
+// of similar sections without a loop at the call site. fn adds its content to
+// md directly, so the output of each iteration follows that of the previous one.
+//
+// Parameters:
+// - n: The number of iterations; nothing happens if n is not positive
+// - fn: A function that adds the content for iteration i to the document
+//
+// Returns:
+// - *Markdown: The document itself, to allow chaining
+func (md *Markdown) Repeat(n int, fn func(md *Markdown, i int)) *Markdown {
+    if fn == nil {
+        return md
+    }
+    for i := 0; i < n; i++ {
+        fn(md, i)
+    }
+    return md
+}
+
+// AlertKind identifies the type of a GitHub-style alert.
+type AlertKind string
+
+// Alert kinds supported by GitHub-flavored Markdown.
+const (
+    AlertNote      AlertKind = "NOTE"
+    AlertTip       AlertKind = "TIP"
+    AlertImportant AlertKind = "IMPORTANT"
+    AlertWarning   AlertKind = "WARNING"
+    AlertCaution   AlertKind = "CAUTION"
+)
+
+// Alert inserts a GitHub-style alert, a blockquote starting with the alert kind.
+//
+// Parameters:
+// - kind: The kind of alert, e.g. AlertNote or AlertWarning
+// - text: The text of the alert
+func (md *Markdown) Alert(kind AlertKind, text string) {
+    if kind == "" || text == "" {
+        return // Skip empty alerts
+    }
+    md.write(fmt.Sprintf("> [!%s]\n%s\n\n", kind, prefixLines(text, "> ")))
+}
+
+// AlertBlock inserts a GitHub-style alert whose body is built by fn, so that
+// lists, code blocks, and other blocks can be placed inside the alert.
+//
+// Parameters:
+// - kind: The kind of alert, e.g. AlertNote or AlertWarning
+// - fn: A function that adds the alert body to the given sub-document
+func (md *Markdown) AlertBlock(kind AlertKind, fn func(*Markdown)) {
+    if kind == "" || fn == nil {
+        return // Skip alerts without a kind or body
+    }
+    body := md.render(fn)
+    if body == "" {
+        return // Skip alerts with an empty body
+    }
+    md.write(fmt.Sprintf("> [!%s]\n%s\n\n", kind, prefixLines(body, "> ")))
+}
+
+// admonitionAlerts maps the kinds accepted by Admonition to the GitHub alert
+// used in place of the admonition in GitHub-flavored Markdown.
+var admonitionAlerts = map[string]AlertKind{
+    "note":    AlertNote,
+    "tip":     AlertTip,
+    "info":    AlertImportant,
+    "warning": AlertWarning,
+    "danger":  AlertCaution,
+}
+
+// Admonition inserts a titled callout in the directive syntax used by
+// documentation sites such as Docusaurus, e.g. ":::tip Title". The title
+// defaults to the capitalized kind. GitHub does not support this syntax, so in
+// GitHub-flavored Markdown the callout becomes the closest alert instead, with
+// a custom title as its first, bold line. Unknown kinds are skipped and
+// recorded as an error in strict mode.
+//
+// Parameters:
+// - kind: "note", "tip", "info", "warning", or "danger"
+// - title: The title of the callout, or "" for the default title
+// - content: The content of the callout
+func (md *Markdown) Admonition(kind, title, content string) {
+    content = strings.Trim(content, "\n")
+    if content == "" {
+        return // Skip empty admonitions
+    }
+    alert, ok := admonitionAlerts[kind]
+    if !ok {
+        md.check(fmt.Errorf("markdown: unknown admonition kind %q", kind))
+        return
+    }
+    if md.flavor == GitHubMarkdown {
+        if title != "" {
+            content = "**" + title + "**\n\n" + content
+        }
+        md.Alert(alert, content)
+        return
+    }
+    if title == "" {
+        title = strings.ToUpper(kind[:1]) + kind[1:]
+    }
+    longest := 2 // Nested directives need a shorter fence than the outer one
+    for _, line := range strings.Split(content, "\n") {
+        if n := len(line) - len(strings.TrimLeft(line, ":")); n > longest {
+            longest = n
+        }
+    }
+    fence := strings.Repeat(":", longest+1)
+    md.write(fmt.Sprintf("%s%s %s\n%s\n%s\n\n", fence, kind, title, content, fence))
+}
+
+// sub creates an empty document that shares all settings of md, and the
+// section numbering so that numbered headings continue it. It is used to render
+// nested content that is post-processed before being added to md.
+func (md *Markdown) sub() *Markdown {
+    return &Markdown{
+        flavor:               md.flavor,
+        useColor:             md.useColor,
+        repoURL:              md.repoURL,
+        extractLinks:         md.extractLinks,
+        htmlOutput:           md.htmlOutput,
+        strictURLs:           md.strictURLs,
+        strict:               md.strict,
+        precision:            md.precision,
+        tildeFences:          md.tildeFences,
+        pageBreak:            md.pageBreak,
+        headingNumbers:       md.headingNumbers,
+        sectionCounters:      md.sectionCounters, // Numbering continues in nested content
+        headingOffset:        md.headingOffset,
+        autoBackToTop:        md.autoBackToTop,
+        deriveTaskStatus:     md.deriveTaskStatus,
+        unicodeEmoji:         md.unicodeEmoji,
+        wrapWidth:            md.wrapWidth,
+        smartTypography:      md.smartTypography,
+        palette:              md.palette,
+        escapeTemplateValues: md.escapeTemplateValues,
+        skipHiddenFiles:      md.skipHiddenFiles,
+        superscriptFootnotes: md.superscriptFootnotes,
+        tabStyle:             md.tabStyle,
+        baseURL:              md.baseURL,
+        blockSpacing:         1, // Nested content keeps the canonical spacing
+        collapsibleTOC:       md.collapsibleTOC,
+        tocOptions:           md.tocOptions,
+        autoLinkURLs:         md.autoLinkURLs,
+        autoEscape:           md.autoEscape,
+        ruleStyle:            md.ruleStyle,
+        alignedTables:        md.alignedTables,
+    }
+}
+
+// render runs fn against a sub-document and returns the produced content
+// without trailing newlines. Errors recorded by the sub-document and its
+// section numbering are carried over to md.
+func (md *Markdown) render(fn func(*Markdown)) string {
+    sub := md.sub()
+    fn(sub)
+    md.check(sub.err)
+    md.lock()
+    md.sectionCounters = sub.sectionCounters // Later headings continue the numbering
+    md.unlock()
+    return strings.TrimRight(sub.content.String(), "\n")
+}
+
+// prefixLines prefixes every line of text with prefix. Empty lines receive the
+// prefix without trailing spaces.
+func prefixLines(text, prefix string) string {
+    lines := strings.Split(text, "\n")
+    for i, line := range lines {
+        if line == "" {
+            lines[i] = strings.TrimRight(prefix, " ")
+        } else {
+            lines[i] = prefix + line
+        }
+    }
+    return strings.Join(lines, "\n")
+}
+
+// SetRuleStyle selects the marker of horizontal rules. "***" and "___" cannot
+// be mistaken for the underline of a setext heading, unlike the default "---".
+// Other styles are ignored and recorded as an error in strict mode.
+//
+// Parameters:
+// - style: "---", "***", or "___"
+func (md *Markdown) SetRuleStyle(style string) {
+    switch style {
+    case "---", "***", "___":
+        md.ruleStyle = style
+    default:
+        md.check(fmt.Errorf("markdown: unknown rule style %q", style))
+    }
+}
+
+// HorizontalRule inserts a horizontal rule into the Markdown content. A blank
+// line is inserted before the rule if the content does not end with one, so
+// that "---" cannot turn the preceding line into a setext heading.
+func (md *Markdown) HorizontalRule() {
+    rule := md.ruleStyle
+    if rule == "" {
+        rule = "---"
+    }
+    if md.needsBlankLine() {
+        rule = "\n" + rule // Separate the rule from a preceding line
+    }
+    md.write(rule + "\n\n")
+}
+
+// defaultPageBreak is the snippet PageBreak emits unless overridden.
+const defaultPageBreak = `<div style="page-break-after: always;"></div>`
+
+// SetPageBreak overrides the raw HTML snippet emitted by PageBreak, e.g. for a
+// renderer with its own page break syntax. An empty snippet restores the default.
+//
+// Parameters:
+// - snippet: The raw snippet marking a page break
+func (md *Markdown) SetPageBreak(snippet string) {
+    md.pageBreak = snippet
+}
+
+// PageBreak inserts a page break marker for print or PDF export.
+func (md *Markdown) PageBreak() {
+    snippet := md.pageBreak
+    if snippet == "" {
+        snippet = defaultPageBreak
+    }
+    md.write(snippet + "\n\n")
+}
+
+// Footnote adds a footnote to the Markdown content with a clickable back reference.
+// A footnote without label or text is skipped, which strict mode records as an
+// error.
+//
+// Parameters:
+// - label: The label for the footnote
+// - text: The content of the footnote
+func (md *Markdown) Footnote(label, text string) {
+    if label == "" || text == "" {
+        md.check(fmt.Errorf("markdown: footnote label or text is empty"))
+        return // Skip invalid footnotes
+    }
+    md.writeFootnote(fmt.Sprintf("[%s]: %s [Return to text](#fn-%s-back)\n", label, text, label))
+}
+
+// MultiLineFootnote creates a multi-line footnote with a back reference.
+//
+// Parameters:
+// - label: The label for the footnote
+// - lines: A slice of strings representing lines in the footnote
+func (md *Markdown) MultiLineFootnote(label string, lines []string) {
+    if label == "" || len(lines) == 0 {
+        return // Skip invalid multi-line footnotes
+    }
+    var b strings.Builder
+    b.WriteString(fmt.Sprintf("[%s]: ", label))
+    for _, line := range lines {
+        b.WriteString(line + "\n")
+    }
+    b.WriteString(fmt.Sprintf("[Return to text](#fn-%s-back)\n\n", label))
+    md.writeFootnote(b.String())
+}
+
+// SetSuperscriptFootnotes controls whether FootnoteRef wraps footnote markers in
+// <sup>, so that they are raised even where the renderer does not superscript
+// footnote references itself.
+//
+// Parameters:
+// - enabled: Whether footnote markers are wrapped in <sup>
+func (md *Markdown) SetSuperscriptFootnotes(enabled bool) {
+    md.superscriptFootnotes = enabled
+}
+
+// FootnoteRef returns a reference to the footnote with the given label, e.g.
+// "[^1]", for use within other text.
+//
+// Parameters:
+// - label: The label of the footnote
+//
+// Returns:
+// - string: The footnote reference, or "" if the label is empty
+func (md *Markdown) FootnoteRef(label string) string {
+    if label == "" {
+        return ""
+    }
+    ref := "[^" + label + "]"
+    if md.superscriptFootnotes {
+        ref = "<sup>" + ref + "</sup>"
+    }
+    return ref
+}
+
+// AutoFootnote allocates the next numeric footnote label, collects the footnote
+// text for RenderFootnotes, and returns the reference to embed, e.g. "[^3]".
+//
+// Parameters:
+// - text: The content of the footnote
+//
+// Returns:
+// - string: The footnote reference, or "" if the text is empty
+func (md *Markdown) AutoFootnote(text string) string {
+    if text == "" {
+        return ""
+    }
+    md.lock()
+    md.footnoteCount++
+    label := strconv.Itoa(md.footnoteCount)
+    md.autoFootnotes = append(md.autoFootnotes, text)
+    md.unlock()
+    return md.FootnoteRef(label)
+}
+
+// RenderFootnotes writes the definitions of the footnotes allocated by
+// AutoFootnote since the last call, in allocation order. Numbering continues
+// across calls.
+func (md *Markdown) RenderFootnotes() {
+    md.lock()
+    pending := md.autoFootnotes
+    first := md.footnoteCount - len(pending) + 1
+    md.autoFootnotes = nil
+    md.unlock()
+    if len(pending) == 0 {
+        return // Nothing to render
+    }
+    var b strings.Builder
+    for i, text := range pending {
+        b.WriteString(fmt.Sprintf("[^%d]: %s\n", first+i, text))
+    }
+    md.writeFootnote(b.String() + "\n")
+}
+
+// writeFootnote appends a footnote definition, or collects it for the end of
+// the document when footnotes are deferred.
+func (md *Markdown) writeFootnote(s string) {
+    if !md.deferFootnotes {
+        md.write(s)
+        return
+    }
+    md.lock()
+    md.footnotes.WriteString(s)
+    md.unlock()
+}
+
+// OrderedDefinition is a struct for holding terms and their definitions in ordered lists.
+// A definition may consist of several paragraphs separated by blank lines.
+type OrderedDefinition struct {
+    term        string
+    definitions []string
+}
+
+// DefinitionList creates a definition list with terms and definitions in Markdown.
+//
+// Parameters:
+// - definitions: A map where each key is a term and its value is a slice of definitions
+func (md *Markdown) DefinitionList(definitions map[string][]string) {
+    if len(definitions) == 0 {
+        return // Skip empty definitions
+    }
+    var b strings.Builder
+    orderedDefs := []OrderedDefinition{
+        {term: "Term 1", definitions: definitions["Term 1"]},
+        {term: "Term 2", definitions: definitions["Term 2"]},
+    }
+    for _, def := range orderedDefs {
+        if def.term == "" || len(def.definitions) == 0 {
+            continue // Skip invalid terms
+        }
+        b.WriteString(fmt.Sprintf("%s\n", def.term))
+        for _, definition := range def.definitions {
+            b.WriteString(fmt.Sprintf(": %s\n", definition))
+        }
+        b.WriteString("\n")
+    }
+    md.write(b.String())
+}
+
+// NewOrderedDefinition creates a term with its definitions for
+// DefinitionListPandoc.
+//
+// Parameters:
+// - term: The term being defined
+// - definitions: The definitions of the term, which may span several lines
+//
+// Returns:
+// - OrderedDefinition: The term with its definitions
+func NewOrderedDefinition(term string, definitions ...string) OrderedDefinition {
+    return OrderedDefinition{term: term, definitions: definitions}
+}
+
+// NewOrderedDefinitionParagraphs creates a term whose definitions consist of
+// several paragraphs each, for DefinitionListPandoc.
+//
+// Parameters:
+// - term: The term being defined
+// - definitions: The paragraphs of each definition
+//
+// Returns:
+// - OrderedDefinition: The term with its definitions
+func NewOrderedDefinitionParagraphs(term string, definitions [][]string) OrderedDefinition {
+    def := OrderedDefinition{term: term}
+    for _, paragraphs := range definitions {
+        if len(paragraphs) > 0 {
+            def.definitions = append(def.definitions, strings.Join(paragraphs, "\n\n"))
+        }
+    }
+    return def
+}
+
+// DefinitionListPandoc creates a definition list following Pandoc's layout:
+// each term is followed by a blank line and its definitions, which start with
+// ":   " and indent continuation lines and further paragraphs by four spaces.
+// Terms keep their order.
+//
+// Parameters:
+// - defs: The terms and their definitions
+func (md *Markdown) DefinitionListPandoc(defs []OrderedDefinition) {
+    var b strings.Builder
+    for _, def := range defs {
+        if def.term == "" || len(def.definitions) == 0 {
+            continue // Skip invalid terms
+        }
+        b.WriteString(def.term + "\n\n")
+        for _, definition := range def.definitions {
+            b.WriteString(":   " + strings.TrimPrefix(prefixLines(definition, "    "), "    ") + "\n")
+        }
+        b.WriteString("\n")
+    }
+    if b.Len() == 0 {
+        return // Skip empty definition lists
+    }
+    md.write(b.String())
+}
+
+// LineBlock inserts a Pandoc line block, which preserves line breaks, e.g. for
+// poems or addresses. Leading spaces are written as escaped spaces (non-breaking
+// in Pandoc) so that indentation survives; a tab counts as four spaces.
+//
+// Parameters:
+// - lines: The lines of the block
+func (md *Markdown) LineBlock(lines []string) {
+    if len(lines) == 0 {
+        return // Skip empty line blocks
+    }
+    var b strings.Builder
+    for _, line := range lines {
+        text := strings.TrimLeft(line, " \t")
+        indent := strings.ReplaceAll(line[:len(line)-len(text)], "\t", "    ")
+        if text == "" {
+            b.WriteString("|\n")
+            continue
+        }
+        b.WriteString("| " + strings.Repeat("\\ ", len(indent)) + text + "\n")
+    }
+    md.write(b.String() + "\n")
+}
+
+// Cite returns a Pandoc citation of the reference with the given key, e.g.
+// "[@knuth1984]", and records the key for Bibliography.
+//
+// Parameters:
+// - key: The key of the cited reference
+//
+// Returns:
+// - string: The citation, or "" if the key is empty
+func (md *Markdown) Cite(key string) string {
+    if key == "" {
+        return ""
+    }
+    md.lock()
+    cited := false
+    for _, k := range md.citations {
+        cited = cited || k == key
+    }
+    if !cited {
+        md.citations = append(md.citations, key)
+    }
+    md.unlock()
+    return "[@" + key + "]"
+}
+
+// Bibliography inserts a "References" section listing the entries sorted by
+// key. Entries that were never cited and citations without an entry are
+// reported by Warnings.
+//
+// Parameters:
+// - entries: The formatted references, keyed by citation key
+func (md *Markdown) Bibliography(entries map[string]string) {
+    md.lock()
+    for _, key := range md.citations {
+        if _, ok := entries[key]; !ok {
+            md.warnings = append(md.warnings, fmt.Sprintf("markdown: citation %q has no bibliography entry", key))
+        }
+    }
+    keys := make([]string, 0, len(entries))
+    for key := range entries {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        cited := false
+        for _, k := range md.citations {
+            cited = cited || k == key
+        }
+        if !cited {
+            md.warnings = append(md.warnings, fmt.Sprintf("markdown: reference %q is never cited", key))
+        }
+    }
+    md.unlock()
+    if len(keys) == 0 {
+        return // Skip empty bibliographies
+    }
+    var b strings.Builder
+    for _, key := range keys {
+        b.WriteString(fmt.Sprintf("- **%s**: %s\n", key, entries[key]))
+    }
+    md.Heading(2, "References", "", "")
+    md.write(b.String() + "\n")
+}
+
+// Warnings returns the problems noticed while rendering that do not prevent the
+// document from being generated, such as uncited references.
+//
+// Returns:
+// - []string: The warnings in the order they were found
+func (md *Markdown) Warnings() []string {
+    md.lock()
+    defer md.unlock()
+    return append([]string(nil), md.warnings...)
+}
+
+// KV is a key-value pair rendered by KeyValues. Values are escaped unless Raw
+// is set, in which case they may contain Markdown.
+type KV struct {
+    Key   string
+    Value string
+    Raw   bool
+}
+
+// KeyValues renders key-value pairs as "**Key:** Value" lines in the given
+// order, separated by hard line breaks, e.g. for metadata in API docs.
+// Pairs without a key are skipped.
+//
+// Parameters:
+// - pairs: The key-value pairs
+func (md *Markdown) KeyValues(pairs []KV) {
+    var lines []string
+    for _, kv := range pairs {
+        if kv.Key == "" {
+            continue // Skip pairs without a key
+        }
+        value := kv.Value
+        if !kv.Raw {
+            value = md.Escape(value)
+        }
+        lines = append(lines, fmt.Sprintf("**%s:** %s", kv.Key, value))
+    }
+    if len(lines) == 0 {
+        return // Skip empty blocks
+    }
+    md.write(strings.Join(lines, "\\\n") + "\n\n")
+}
+
+// Release describes a released version rendered by Changelog.
+type Release struct {
+    Version string
+    Date    string
+    Added   []string
+    Changed []string
+    Fixed   []string
+}
+
+// Changelog renders release notes in the Keep a Changelog style: a "Changelog"
+// heading followed by a level-2 heading per release, e.g. "[1.2.0] - 2024-05-01",
+// and a bullet list per category. Empty categories and releases without a
+// version are omitted.
+//
+// Parameters:
+// - versions: The releases, usually newest first
+func (md *Markdown) Changelog(versions []Release) {
+    var releases []Release
+    for _, r := range versions {
+        if r.Version != "" {
+            releases = append(releases, r)
+        }
+    }
+    if len(releases) == 0 {
+        return // Skip empty changelogs
+    }
+    md.Heading(1, "Changelog", "", "")
+    for _, r := range releases {
+        title := "[" + r.Version + "]"
+        if r.Date != "" {
+            title += " - " + r.Date
+        }
+        md.Heading(2, title, "", "")
+        for _, category := range []struct {
+            name    string
+            entries []string
+        }{{"Added", r.Added}, {"Changed", r.Changed}, {"Fixed", r.Fixed}} {
+            if len(category.entries) > 0 {
+                md.Heading(3, category.name, "", "")
+                md.List(category.entries, false)
+            }
+        }
+    }
+}
+
+// Commit describes a single commit rendered by GitLog.
+type Commit struct {
+    Hash    string
+    Subject string
+    Author  string
+}
+
+// GitLog renders a list of commits as a bullet list, e.g. for release notes.
+// Each entry shows the short hash in inline code, linked to the commit page
+// when a repository URL has been set with SetRepoURL.
+//
+// Parameters:
+// - commits: The commits to render in the given order
+func (md *Markdown) GitLog(commits []Commit) {
+    if len(commits) == 0 {
+        return // Skip empty logs
+    }
+    var b strings.Builder
+    for _, commit := range commits {
+        if commit.Hash == "" || commit.Subject == "" {
+            continue // Skip incomplete commits
+        }
+        short := commit.Hash
+        if len(short) > 7 {
+            short = short[:7]
+        }
+        hash := "`" + short + "`"
+        if md.repoURL != "" {
+            hash = fmt.Sprintf("[%s](%s/commit/%s)", hash, md.repoURL, commit.Hash)
+        }
+        entry := fmt.Sprintf("- %s %s", hash, commit.Subject)
+        if commit.Author != "" {
+            entry += fmt.Sprintf(" (%s)", commit.Author)
+        }
+        b.WriteString(entry + "\n")
+    }
+    b.WriteString("\n")
+    md.write(b.String())
+}
+
+// VersionComparison inserts a sentence linking to the repository's compare view
+// for the range between two versions, e.g. for release notes.
+//
+// Parameters:
+// - from: The earlier version or tag
+// - to: The later version or tag
+// - repoBase: The repository URL, e.g. "https://github.com/user/repo"
+func (md *Markdown) VersionComparison(from, to, repoBase string) {
+    if from == "" || to == "" {
+        md.check(fmt.Errorf("markdown: version comparison needs both versions"))
+        return // Skip incomplete ranges
+    }
+    if repoBase == "" {
+        md.check(fmt.Errorf("markdown: version comparison needs a repository URL"))
+        return // Skip comparisons without a repository
+    }
+    versions := from + "..." + to
+    md.write(fmt.Sprintf("Full changelog: [%s](%s/compare/%s)\n\n", versions, strings.TrimSuffix(repoBase, "/"), versions))
+}
+
+// Metric describes a single key figure rendered by Metrics. A positive Delta is
+// shown with ▲, a negative one with ▼.
+type Metric struct {
+    Label string
+    Value string
+    Delta float64
+}
+
+// Metrics renders a row of key figures, e.g. for dashboards. In HTML output mode
+// each metric becomes a card; otherwise the metrics are rendered as a table.
+// Deltas are colored green or red when color support is enabled.
 //
 // Parameters:
-// - headers: A slice of strings for the table headers
-// - rows: A 2D slice representing rows in the table
-// - align: A slice for alignment settings ("left", "center", or "right") for each column
-func (md *Markdown) Table(headers []string, rows [][]string, align []string) {
-    if len(headers) == 0 || len(rows) == 0 {
-        return // Skip empty tables
+// - metrics: The metrics to render in the given order
+func (md *Markdown) Metrics(metrics []Metric) {
+    if len(metrics) == 0 {
+        return // Skip empty metric rows
     }
-    headerLine := "| " + strings.Join(headers, " | ") + " |\n"
-    alignment := "|"
-    for _, a := range align {
-        switch a {
-        case "left":
-            alignment += ":---|"
-        case "center":
-            alignment += ":---:|"
-        case "right":
-            alignment += "---:|"
+    if !md.htmlOutput {
+        rows := make([][]string, 0, len(metrics))
+        for _, m := range metrics {
+            rows = append(rows, []string{m.Label, m.Value, md.formatDelta(m.Delta)})
+        }
+        md.Table([]string{"Metric", "Value", "Change"}, rows, []string{"left", "right", "right"})
+        return
+    }
+    var b strings.Builder
+    b.WriteString("<div style=\"display: flex; gap: 1em;\">\n")
+    for _, m := range metrics {
+        b.WriteString(fmt.Sprintf("<div style=\"border: 1px solid #ccc; padding: 0.5em 1em;\"><strong>%s</strong><br>%s<br>%s</div>\n",
+            html.EscapeString(m.Label), html.EscapeString(m.Value), md.formatDelta(m.Delta)))
+    }
+    b.WriteString("</div>\n\n")
+    md.write(b.String())
+}
+
+// gistURLPattern matches the URLs of GitHub gists, capturing the owner and the
+// gist ID.
+var gistURLPattern = regexp.MustCompile(`^https://gist\.github\.com/([A-Za-z0-9-]+)/([0-9a-f]+)/?$`)
+
+// GistEmbed references a GitHub gist. In HTML output mode the gist is embedded
+// with GitHub's script tag, which renderers that allow scripts display inline;
+// otherwise a link to the gist is inserted. URLs that are not gist URLs are
+// skipped and recorded as an error in strict mode.
+//
+// Parameters:
+// - gistURL: The URL of the gist, e.g. https://gist.github.com/user/0123abcd
+func (md *Markdown) GistEmbed(gistURL string) {
+    m := gistURLPattern.FindStringSubmatch(strings.TrimSuffix(gistURL, ".js"))
+    if m == nil {
+        md.check(fmt.Errorf("markdown: %q is not a gist URL", gistURL))
+        return
+    }
+    canonical := "https://gist.github.com/" + m[1] + "/" + m[2]
+    if md.htmlOutput {
+        md.HTMLBlock(fmt.Sprintf("<script src=\"%s.js\"></script>", canonical))
+        return
+    }
+    md.write(fmt.Sprintf("[Gist %s/%s](%s)\n\n", m[1], m[2], canonical))
+}
+
+// formatDelta renders a metric delta with a direction marker and optional color.
+func (md *Markdown) formatDelta(delta float64) string {
+    switch {
+    case delta > 0:
+        return md.ColorText("▲ "+md.formatNumber(delta), "green")
+    case delta < 0:
+        return md.ColorText("▼ "+md.formatNumber(-delta), "red")
+    }
+    return "0"
+}
+
+// GlossaryEntry is a term with its definition.
+type GlossaryEntry struct {
+    Term       string
+    Definition string
+}
+
+// GlossarySectioned renders a glossary grouped by the first letter of each term.
+// A navigation line linking to every letter comes first, followed by a level-3
+// heading per letter and the alphabetically sorted entries of that letter.
+// Terms that start with a digit are grouped under "0–9", and terms that start
+// with neither a letter nor a digit under "Other".
+//
+// Parameters:
+// - entries: The glossary entries in any order
+func (md *Markdown) GlossarySectioned(entries []GlossaryEntry) {
+    var sorted []GlossaryEntry
+    for _, e := range entries {
+        if e.Term != "" && e.Definition != "" {
+            sorted = append(sorted, e) // Skip incomplete entries
+        }
+    }
+    if len(sorted) == 0 {
+        return // Skip empty glossaries
+    }
+    sort.SliceStable(sorted, func(i, j int) bool {
+        return strings.ToLower(sorted[i].Term) < strings.ToLower(sorted[j].Term)
+    })
+    var letters []string
+    groups := map[string][]GlossaryEntry{}
+    for _, e := range sorted {
+        letter := glossaryLetter(e.Term)
+        if _, exists := groups[letter]; !exists {
+            letters = append(letters, letter)
+        }
+        groups[letter] = append(groups[letter], e)
+    }
+    nav := make([]string, 0, len(letters))
+    headers := make([]string, 0, len(letters))
+    backToTop := make([]bool, 0, len(letters))
+    for _, letter := range letters {
+        header, id, back := md.headingLine(3, letter, "", "") // Tracked first, so nav links use the final IDs
+        nav = append(nav, fmt.Sprintf("[%s](#%s)", letter, id))
+        headers = append(headers, header)
+        backToTop = append(backToTop, back)
+    }
+    md.write(strings.Join(nav, " · ") + "\n\n")
+    for i, letter := range letters {
+        if backToTop[i] {
+            md.BackToTop("") // Close the previous section
+        }
+        md.write(headers[i])
+        var b strings.Builder
+        for _, e := range groups[letter] {
+            b.WriteString(fmt.Sprintf("%s\n: %s\n\n", e.Term, e.Definition))
+        }
+        md.write(b.String())
+    }
+}
+
+// Glossary inserts a "Glossary" section listing the terms with their
+// definitions, sorted alphabetically. Each term gets an anchor that GlossaryLink
+// links to. Terms linked before the glossary was added but missing from it are
+// reported by Warnings.
+//
+// Parameters:
+// - terms: The definitions, keyed by term
+func (md *Markdown) Glossary(terms map[string]string) {
+    sorted := make([]string, 0, len(terms))
+    for term, definition := range terms {
+        if term != "" && definition != "" {
+            sorted = append(sorted, term) // Skip incomplete entries
+        }
+    }
+    if len(sorted) == 0 {
+        return // Skip empty glossaries
+    }
+    sort.Slice(sorted, func(i, j int) bool {
+        return strings.ToLower(sorted[i]) < strings.ToLower(sorted[j])
+    })
+    md.lock()
+    if md.glossaryTerms == nil {
+        md.glossaryTerms = make(map[string]bool)
+    }
+    for _, term := range sorted {
+        md.glossaryTerms[strings.ToLower(term)] = true
+    }
+    for _, term := range md.glossaryLinks {
+        if !md.glossaryTerms[strings.ToLower(term)] {
+            md.warnings = append(md.warnings, fmt.Sprintf("markdown: glossary term %q is not defined", term))
+        }
+    }
+    md.glossaryLinks = nil
+    md.unlock()
+    var b strings.Builder
+    for _, term := range sorted {
+        b.WriteString(fmt.Sprintf("<a id=\"%s\"></a>%s\n: %s\n\n", glossaryAnchor(term), term, terms[term]))
+    }
+    md.Heading(2, "Glossary", "", "")
+    md.write(b.String())
+}
+
+// GlossaryLink returns a link from term to its entry in the glossary. Links to
+// terms that the glossary does not define are reported by Warnings, either
+// immediately or, if no glossary was added yet, when Glossary is called.
+//
+// Parameters:
+// - term: The term to link, matched case-insensitively
+//
+// Returns:
+// - string: The link, or "" if the term is empty
+func (md *Markdown) GlossaryLink(term string) string {
+    if term == "" {
+        return ""
+    }
+    md.lock()
+    switch {
+    case md.glossaryTerms == nil:
+        md.glossaryLinks = append(md.glossaryLinks, term)
+    case !md.glossaryTerms[strings.ToLower(term)]:
+        md.warnings = append(md.warnings, fmt.Sprintf("markdown: glossary term %q is not defined", term))
+    }
+    md.unlock()
+    return fmt.Sprintf("[%s](#%s)", term, glossaryAnchor(term))
+}
+
+// glossaryAnchor returns the anchor of a glossary term.
+func glossaryAnchor(term string) string {
+    return "glossary-" + slugify(term)
+}
+
+// glossaryLetter returns the upper-case first letter of term, "0–9" if term
+// starts with a digit, or "Other" if it starts with neither.
+func glossaryLetter(term string) string {
+    for _, r := range term {
+        switch {
+        case unicode.IsLetter(r):
+            return string(unicode.ToUpper(r))
+        case unicode.IsDigit(r):
+            return "0–9"
+        }
+        break
+    }
+    return "Other"
+}
+
+// markdownEscaper escapes the Markdown special characters, each exactly once.
+// Escape and everything built on it, including auto-escaping, go through it.
+var markdownEscaper = strings.NewReplacer(
+    `\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "{", `\{`, "[", `\[`, "]", `\]`,
+    "}", `\}`, "(", `\(`, ")", `\)`, "#", `\#`, "+", `\+`, "-", `\-`, ".", `\.`, "!", `\!`,
+)
+
+// Escape escapes special characters in Markdown. Each character is escaped
+// exactly once, so that the text renders as given, backslashes included.
+//
+// Parameters:
+// - text: The text to escape
+//
+// Returns:
+// - string: The escaped text
+func (md *Markdown) Escape(text string) string {
+    return markdownEscaper.Replace(text)
+}
+
+// EscapeDocument escapes special characters like Escape, but leaves inline code
+// spans, fenced code blocks, and autolinks such as <https://example.com>
+// untouched, so that user-supplied Markdown can be inserted safely without
+// corrupting its code and links.
+//
+// Parameters:
+// - text: The document to escape
+//
+// Returns:
+// - string: The escaped document
+func (md *Markdown) EscapeDocument(text string) string {
+    return mapOutsideCode(text, func(prose string) string {
+        var out strings.Builder
+        start := 0
+        for _, loc := range autolinkPattern.FindAllStringIndex(prose, -1) {
+            out.WriteString(md.Escape(prose[start:loc[0]]))
+            out.WriteString(prose[loc[0]:loc[1]])
+            start = loc[1]
+        }
+        out.WriteString(md.Escape(prose[start:]))
+        return out.String()
+    })
+}
+
+// WithAutoEscape makes Paragraph, Heading, the list methods including TaskTree,
+// and tables escape Markdown special characters in the text they are given,
+// e.g. to render untrusted input literally. Escaping works like EscapeDocument,
+// so code spans and autolinks in the text stay intact. Markup added by the
+// methods themselves, such as bold table footers, is not escaped, and neither
+// are code blocks and raw content.
+//
+// Parameters:
+// - enabled: Whether text is escaped
+//
+// Returns:
+// - *Markdown: The document itself, to allow chaining
+func (md *Markdown) WithAutoEscape(enabled bool) *Markdown {
+    md.autoEscape = enabled
+    return md
+}
+
+// autolinkPattern matches autolinks of the form <scheme:...>.
+var autolinkPattern = regexp.MustCompile(`<[a-zA-Z][a-zA-Z0-9+.-]*:[^\s<>]*>`)
+
+// escapeText escapes text given to a block method if auto-escaping is enabled.
+func (md *Markdown) escapeText(text string) string {
+    if !md.autoEscape {
+        return text
+    }
+    return md.EscapeDocument(text)
+}
+
+// escapeCells returns the headers and rows of a table with their text escaped
+// if auto-escaping is enabled. The arguments are not modified.
+func (md *Markdown) escapeCells(headers []string, rows [][]string) ([]string, [][]string) {
+    if !md.autoEscape {
+        return headers, rows
+    }
+    return mapCells(headers, rows, md.escapeText)
+}
+
+// mapCells returns the headers and rows of a table with fn applied to every
+// cell. The arguments are not modified.
+func mapCells(headers []string, rows [][]string, fn func(string) string) ([]string, [][]string) {
+    mapAll := func(cells []string) []string {
+        mapped := make([]string, len(cells))
+        for i, cell := range cells {
+            mapped[i] = fn(cell)
+        }
+        return mapped
+    }
+    mappedRows := make([][]string, len(rows))
+    for i, row := range rows {
+        mappedRows[i] = mapAll(row)
+    }
+    return mapAll(headers), mappedRows
+}
+
+// EscapeTableCell prepares text for a pipe table cell: pipes, which would end
+// the cell, are escaped as \|, and line breaks, which would end the row, become
+// <br> tags. Other Markdown is left intact, so inline formatting still works in
+// the cell. Pipes that are already escaped are kept as they are. All pipe
+// tables, including those of Table, TableBuilder, and Gallery, apply this to
+// every cell.
+//
+// Parameters:
+// - text: The content of the cell
+//
+// Returns:
+// - string: The escaped cell content
+func (md *Markdown) EscapeTableCell(text string) string {
+    text = strings.NewReplacer("\r\n", "<br>", "\n", "<br>", "\r", "<br>").Replace(text)
+    var b strings.Builder
+    for i := 0; i < len(text); i++ {
+        switch {
+        case text[i] == '\\' && i+1 < len(text):
+            b.WriteString(text[i : i+2]) // Keep escape sequences, including \|
+            i++
+        case text[i] == '|':
+            b.WriteString("\\|")
         default:
-            alignment += "---|"
+            b.WriteByte(text[i])
         }
     }
-    md.content.WriteString(headerLine + alignment + "\n")
-    for _, row := range rows {
-        if len(row) != len(headers) {
-            continue // Ensure rows match header count
+    return b.String()
+}
+
+// templateTokenPattern matches {{key}} tokens in templates.
+var templateTokenPattern = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+// SetTemplateEscape controls whether Template escapes Markdown special
+// characters in the values it substitutes. Values are inserted as they are by
+// default.
+//
+// Parameters:
+// - enabled: Whether substituted values are escaped
+func (md *Markdown) SetTemplateEscape(enabled bool) {
+    md.escapeTemplateValues = enabled
+}
+
+// Template replaces the {{key}} tokens in tmpl with the matching values and
+// appends the result as a block, ending it with a blank line like the other
+// block methods. Tokens without a value are left intact;
+// in strict mode they are recorded as an error and nothing is appended.
+//
+// Parameters:
+// - tmpl: The template text
+// - vars: The values for the tokens, keyed by token name
+func (md *Markdown) Template(tmpl string, vars map[string]string) {
+    if tmpl == "" {
+        return // Skip empty templates
+    }
+    var missing []string
+    result := templateTokenPattern.ReplaceAllStringFunc(tmpl, func(token string) string {
+        key := templateTokenPattern.FindStringSubmatch(token)[1]
+        value, ok := vars[key]
+        if !ok {
+            missing = append(missing, key)
+            return token
         }
-        md.content.WriteString("| " + strings.Join(row, " | ") + " |\n")
+        if md.escapeTemplateValues {
+            return md.Escape(value)
+        }
+        return value
+    })
+    if len(missing) > 0 && md.strict {
+        md.check(fmt.Errorf("markdown: template has no value for %s", strings.Join(missing, ", ")))
+        return
+    }
+    result = strings.TrimRight(result, "\n")
+    if result == "" {
+        return // Skip templates that produce no content
+    }
+    md.write(result + "\n\n")
+}
+
+// CustomDiv creates a custom div block, often used for notes or warnings.
+//
+// Parameters:
+// - className: CSS class name for styling
+// - content: The inner content of the div
+func (md *Markdown) CustomDiv(className, content string) {
+    if content == "" {
+        return // Skip empty custom divs
+    }
+    md.write(fmt.Sprintf("::: %s\n%s\n:::\n\n", className, content))
+}
+
+// FencedDiv creates a Pandoc fenced div with attributes, e.g.
+// ::: {#id .class1 .class2 key="val"}. The attributes follow the same rules
+// as Span: "id" is the identifier, "class" holds space-separated class names,
+// and all other keys are rendered in alphabetical order. Without attributes
+// the opening fence is a bare ":::".
+//
+// Parameters:
+// - attrs: The attributes of the div
+// - content: The inner content of the div
+func (md *Markdown) FencedDiv(attrs map[string]string, content string) {
+    if content == "" {
+        return // Skip empty divs
+    }
+    open := ":::"
+    if attributes := pandocAttributes(attrs); attributes != "{}" {
+        open += " " + attributes
+    }
+    md.write(fmt.Sprintf("%s\n%s\n:::\n\n", open, content))
+}
+
+// Section wraps the content added by body in an HTML <section> element with the
+// given id. The content is added to md directly, so headings inside the section
+// are tracked as usual, and blank lines around it keep it parsed as Markdown.
+//
+// Parameters:
+// - id: The id of the section, HTML-escaped
+// - body: A function that adds the content of the section
+func (md *Markdown) Section(id string, body func(*Markdown)) {
+    if body == nil {
+        return // Skip sections without content
+    }
+    open := "<section>\n\n"
+    if id != "" {
+        open = fmt.Sprintf("<section id=\"%s\">\n\n", html.EscapeString(id))
+    }
+    if md.needsBlankLine() {
+        open = "\n" + open // Separate the section from a preceding line
+    }
+    md.writeHTMLOpen(open)
+    body(md)
+    closing := "</section>\n"
+    if md.needsBlankLine() {
+        closing = "\n" + closing // End the Markdown content of the section
+    }
+    md.writeHTMLClose(closing)
+}
+
+// NestedSection inserts a heading whose level follows the nesting of
+// NestedSection calls: a top-level call emits an H1, a call within its body an
+// H2, and so on, so that generated sections are always well nested. Sections
+// nested deeper than six levels get an H6. The content added by body follows
+// the heading.
+//
+// Parameters:
+// - title: The text of the section heading
+// - body: A function that adds the content of the section, or nil
+func (md *Markdown) NestedSection(title string, body func(*Markdown)) {
+    md.lock()
+    md.sectionDepth++
+    depth := md.sectionDepth
+    md.unlock()
+    defer func() {
+        md.lock()
+        md.sectionDepth--
+        md.unlock()
+    }()
+    md.Heading(clampLevel(depth), title, "", "")
+    if body != nil {
+        body(md)
+    }
+}
+
+// Columns places the content added by each function side by side in equally
+// wide columns, using flexbox <div> elements. This only has an effect in
+// renderers that allow HTML; elsewhere the columns appear one below the other.
+//
+// Parameters:
+// - cols: Functions that each add the content of one column
+func (md *Markdown) Columns(cols ...func(*Markdown)) {
+    if len(cols) == 0 {
+        return // Skip empty layouts
+    }
+    open := "<div style=\"display: flex; gap: 1em;\">\n"
+    if md.needsBlankLine() {
+        open = "\n" + open // Separate the layout from a preceding line
+    }
+    md.write(open)
+    for _, col := range cols {
+        md.writeHTMLOpen("<div style=\"flex: 1;\">\n\n")
+        if col != nil {
+            col(md)
+        }
+        closing := "</div>\n"
+        if md.needsBlankLine() {
+            closing = "\n" + closing // End the Markdown content of the column
+        }
+        md.write(closing)
+    }
+    md.writeHTMLClose("</div>\n")
+}
+
+// TabStyle selects the output format of Tabs.
+type TabStyle int
+
+// Tab styles supported by Tabs.
+const (
+    TabsMkDocs  TabStyle = iota // MkDocs Material content tabs: === "Title"
+    TabsDetails                 // HTML <details> elements, one per tab
+)
+
+// Tab is a titled panel rendered by Tabs.
+type Tab struct {
+    Title string
+    Body  func(*Markdown)
+}
+
+// SetTabStyle selects the output format of Tabs. TabsMkDocs is the default.
+//
+// Parameters:
+// - style: The tab style to use
+func (md *Markdown) SetTabStyle(style TabStyle) {
+    md.tabStyle = style
+}
+
+// Tabs inserts tabbed panels. By default the MkDocs Material syntax is used,
+// where each tab starts with === "Title" followed by its content indented by
+// four spaces; with TabsDetails each tab becomes a collapsible <details>
+// element, which works in any renderer that allows HTML. Tabs without a title
+// or body are skipped.
+//
+// Parameters:
+// - tabs: The tabs in display order
+func (md *Markdown) Tabs(tabs []Tab) {
+    var b strings.Builder
+    for _, tab := range tabs {
+        if tab.Title == "" || tab.Body == nil {
+            continue // Skip invalid tabs
+        }
+        body := md.render(tab.Body)
+        if md.tabStyle == TabsDetails {
+            b.WriteString(fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s\n\n</details>\n\n", html.EscapeString(tab.Title), body))
+            continue
+        }
+        b.WriteString(fmt.Sprintf("=== %q\n\n%s\n\n", tab.Title, prefixLines(body, "    ")))
+    }
+    if b.Len() == 0 {
+        return // Skip empty tab sets
+    }
+    md.write(b.String())
+}
+
+// ProgressBar inserts a progress indicator such as "█████░░░░░ 50%". In HTML
+// output mode a <progress> element is emitted instead.
+//
+// Parameters:
+// - percent: The progress in percent, clamped to 0-100
+// - width: The number of characters of the bar, 20 if not positive
+func (md *Markdown) ProgressBar(percent int, width int) {
+    if percent < 0 {
+        percent = 0
+    } else if percent > 100 {
+        percent = 100
+    }
+    if md.htmlOutput {
+        md.write(fmt.Sprintf("<progress value=\"%d\" max=\"100\">%d%%</progress>\n\n", percent, percent))
+        return
+    }
+    if width <= 0 {
+        width = 20
+    }
+    filled := (percent*width + 50) / 100 // Round to the nearest character
+    md.write(fmt.Sprintf("%s%s %d%%\n\n", strings.Repeat("█", filled), strings.Repeat("░", width-filled), percent))
+}
+
+// TaskList creates a Markdown task list.
+//
+// Parameters:
+// - items: A slice of task items
+// - checked: A slice of booleans indicating completion status
+func (md *Markdown) TaskList(items []string, checked []bool) {
+    if len(items) == 0 {
+        return // Skip empty task lists
+    }
+    var b strings.Builder
+    for i, item := range items {
+        if item == "" {
+            continue // Skip empty items
+        }
+        check := " "
+        if i < len(checked) && checked[i] {
+            check = "x"
+        }
+        b.WriteString(fmt.Sprintf("- [%s] %s\n", check, md.escapeText(item)))
+    }
+    b.WriteString("\n")
+    md.write(b.String())
+}
+
+// TaskListWithSummary creates a task list followed by a summary line such as
+// "3 of 5 complete (60%)". Items without a matching checked value count as open.
+//
+// Parameters:
+// - items: A slice of task items
+// - checked: A slice of booleans indicating completion status
+func (md *Markdown) TaskListWithSummary(items []string, checked []bool) {
+    total, done := 0, 0
+    for i, item := range items {
+        if item == "" {
+            continue // Empty items are not rendered
+        }
+        total++
+        if i < len(checked) && checked[i] {
+            done++
+        }
+    }
+    if total == 0 {
+        return // Skip empty task lists
+    }
+    md.TaskList(items, checked)
+    md.write(fmt.Sprintf("%d of %d complete (%d%%)\n\n", done, total, done*100/total))
+}
+
+// Task is an entry of a task tree with optional subtasks.
+type Task struct {
+    Text     string
+    Done     bool
+    Subtasks []Task
+}
+
+// SetDeriveTaskStatus controls whether TaskTree derives the completion of tasks
+// with subtasks from their children: such a task is done when all of its
+// subtasks are done, regardless of its own Done field.
+//
+// Parameters:
+// - enabled: Whether parent completion should be derived
+func (md *Markdown) SetDeriveTaskStatus(enabled bool) {
+    md.deriveTaskStatus = enabled
+}
+
+// TaskTree creates a nested task list. Subtasks are indented by two spaces per
+// level below their parent task.
+//
+// Parameters:
+// - tasks: The top-level tasks
+func (md *Markdown) TaskTree(tasks []Task) {
+    var b strings.Builder
+    md.writeTasks(&b, tasks, 0)
+    if b.Len() == 0 {
+        return // Skip empty task trees
+    }
+    md.write(b.String() + "\n")
+}
+
+// writeTasks renders tasks at the given depth, including their subtasks.
+func (md *Markdown) writeTasks(b *strings.Builder, tasks []Task, depth int) {
+    for _, task := range tasks {
+        if task.Text == "" {
+            continue // Skip empty tasks along with their subtasks
+        }
+        check := " "
+        if md.taskDone(task) {
+            check = "x"
+        }
+        b.WriteString(fmt.Sprintf("%s- [%s] %s\n", strings.Repeat("  ", depth), check, md.escapeText(task.Text)))
+        md.writeTasks(b, task.Subtasks, depth+1)
+    }
+}
+
+// taskDone reports whether a task is complete, deriving the status from its
+// subtasks if enabled.
+func (md *Markdown) taskDone(task Task) bool {
+    if !md.deriveTaskStatus || len(task.Subtasks) == 0 {
+        return task.Done
     }
-    md.content.WriteString("\n")
+    for _, sub := range task.Subtasks {
+        if sub.Text != "" && !md.taskDone(sub) {
+            return false
+        }
+    }
+    return true
+}
+
+// ListItem is an entry of a hierarchical list with optional child items.
+type ListItem struct {
+    Text     string
+    Children []ListItem
 }
 
-// Blockquote inserts a blockquote into the Markdown content.
+// OrderedTree creates a hierarchically numbered list such as 1., 1.1, 1.1.1,
+// e.g. for the sections of a specification. Markdown lists cannot produce these
+// numbers, so, like the literal markers of OrderedListStyle, each item is a line
+// starting with its number, separated from the next by a hard line break.
+// Nested items are indented with non-breaking spaces. The numbers of child
+// items restart at 1 under each parent. Items without text are skipped along
+// with their children.
 //
 // Parameters:
-// - text: The text for the blockquote
-func (md *Markdown) Blockquote(text string) {
-    if text == "" {
-        return // Skip empty blockquotes
+// - items: The top-level items
+func (md *Markdown) OrderedTree(items []ListItem) {
+    var lines []string
+    md.writeOrderedTree(&lines, items, "", 0)
+    if len(lines) == 0 {
+        return // Skip empty trees
     }
-    md.content.WriteString("> " + text + "\n\n")
+    md.write(strings.Join(lines, "\\\n") + "\n\n")
 }
 
-// HorizontalRule inserts a horizontal rule into the Markdown content.
-func (md *Markdown) HorizontalRule() {
-    md.content.WriteString("---\n\n")
+// writeOrderedTree renders items below the number prefix at the given depth,
+// including their children, as one line per item.
+func (md *Markdown) writeOrderedTree(lines *[]string, items []ListItem, prefix string, depth int) {
+    n := 0
+    for _, item := range items {
+        if item.Text == "" {
+            continue // Skip empty items along with their children
+        }
+        n++
+        number := prefix + strconv.Itoa(n)
+        marker := number
+        if depth == 0 {
+            marker += "\\." // Escaped so that the line does not start a list
+        }
+        indent := strings.Repeat(md.NBSP(), 4*depth)
+        *lines = append(*lines, indent+marker+" "+md.escapeText(item.Text))
+        md.writeOrderedTree(lines, item.Children, number+".", depth+1)
+    }
 }
 
-// Footnote adds a footnote to the Markdown content with a clickable back reference.
+// WithUnicodeEmoji enables or disables the output of emoji as Unicode
+// characters. By default Emoji emits shortcodes such as ":smile:", which only
+// render on platforms that support them. Unknown shortcodes are always emitted
+// as shortcodes.
 //
 // Parameters:
-// - label: The label for the footnote
-// - text: The content of the footnote
-func (md *Markdown) Footnote(label, text string) {
-    if label == "" || text == "" {
-        return // Skip invalid footnotes
-    }
-    md.content.WriteString(fmt.Sprintf("[%s]: %s [Return to text](#fn-%s-back)\n", label, text, label))
+// - enabled: Whether emoji should be output as Unicode characters
+//
+// Returns:
+// - *Markdown: The document itself, to allow chaining
+func (md *Markdown) WithUnicodeEmoji(enabled bool) *Markdown {
+    md.unicodeEmoji = enabled
+    return md
 }
 
-// MultiLineFootnote creates a multi-line footnote with a back reference.
+// Emoji inserts an emoji given by its shortcode, e.g. "smile" or ":smile:".
+// Unknown shortcodes are still emitted but counted, see UnknownEmojiCount; in
+// strict mode they are recorded as an error instead.
 //
 // Parameters:
-// - label: The label for the footnote
-// - lines: A slice of strings representing lines in the footnote
-func (md *Markdown) MultiLineFootnote(label string, lines []string) {
-    if label == "" || len(lines) == 0 {
-        return // Skip invalid multi-line footnotes
-    }
-    md.content.WriteString(fmt.Sprintf("[%s]: ", label))
-    for _, line := range lines {
-        md.content.WriteString(line + "\n")
+// - code: The emoji shortcode, with or without surrounding colons
+func (md *Markdown) Emoji(code string) {
+    err := md.EmojiE(code)
+    if err != nil && !md.strict && strings.Trim(code, ":") != "" {
+        md.lock()
+        md.unknownEmoji++
+        md.unlock()
+        md.write(md.emoji(strings.Trim(code, ":")) + "\n\n")
+        return
     }
-    md.content.WriteString(fmt.Sprintf("[Return to text](#fn-%s-back)\n\n", label))
-}
-
-// OrderedDefinition is a struct for holding terms and their definitions in ordered lists.
-type OrderedDefinition struct {
-    term        string
-    definitions []string
+    md.check(err)
 }
 
-// DefinitionList creates a definition list with terms and definitions in Markdown.
+// EmojiE works like Emoji but rejects unknown shortcodes with an error.
 //
 // Parameters:
-// - definitions: A map where each key is a term and its value is a slice of definitions
-func (md *Markdown) DefinitionList(definitions map[string][]string) {
-    if len(definitions) == 0 {
-        return // Skip empty definitions
+// - code: The emoji shortcode, with or without surrounding colons
+//
+// Returns:
+// - error: A description of why the emoji was rejected, or nil
+func (md *Markdown) EmojiE(code string) error {
+    if err := md.Err(); err != nil {
+        return err
     }
-    orderedDefs := []OrderedDefinition{
-        {term: "Term 1", definitions: definitions["Term 1"]},
-        {term: "Term 2", definitions: definitions["Term 2"]},
+    code = strings.Trim(code, ":")
+    if code == "" {
+        return fmt.Errorf("markdown: emoji shortcode is empty")
     }
-    for _, def := range orderedDefs {
-        if def.term == "" || len(def.definitions) == 0 {
-            continue // Skip invalid terms
-        }
-        md.content.WriteString(fmt.Sprintf("%s\n", def.term))
-        for _, definition := range def.definitions {
-            md.content.WriteString(fmt.Sprintf(": %s\n", definition))
-        }
-        md.content.WriteString("\n")
+    if !md.IsValidEmoji(code) {
+        return fmt.Errorf("markdown: unknown emoji shortcode %q", code)
     }
+    md.write(md.emoji(code) + "\n\n")
+    return nil
 }
 
-// Escape escapes special characters in Markdown.
+// IsValidEmoji reports whether code is a known emoji shortcode.
 //
 // Parameters:
-// - text: The text to escape
+// - code: The emoji shortcode, with or without surrounding colons
 //
 // Returns:
-// - string: The escaped text
-func (md *Markdown) Escape(text string) string {
-    specialChars := `\\` + "`*_{[]}()#+-.!"
-    for _, char := range specialChars {
-        text = strings.ReplaceAll(text, string(char), "\\"+string(char))
-    }
-    return text
+// - bool: True if the shortcode is in the bundled shortcode table
+func (md *Markdown) IsValidEmoji(code string) bool {
+    _, ok := emojiShortcodes[strings.Trim(code, ":")]
+    return ok
 }
 
-// CustomDiv creates a custom div block, often used for notes or warnings.
+// UnknownEmojiCount returns how many unknown shortcodes were passed to Emoji
+// outside strict mode, which helps to catch typos such as ":smille:".
 //
-// Parameters:
-// - className: CSS class name for styling
-// - content: The inner content of the div
-func (md *Markdown) CustomDiv(className, content string) {
-    if content == "" {
-        return // Skip empty custom divs
-    }
-    md.content.WriteString(fmt.Sprintf("::: %s\n%s\n:::\n\n", className, content))
+// Returns:
+// - int: The number of unknown shortcodes emitted
+func (md *Markdown) UnknownEmojiCount() int {
+    md.lock()
+    defer md.unlock()
+    return md.unknownEmoji
 }
 
-// TaskList creates a Markdown task list.
+// EmojiInline returns an emoji for embedding in other text, such as a
+// paragraph or heading. Unknown shortcodes are handled as in Emoji.
 //
 // Parameters:
-// - items: A slice of task items
-// - checked: A slice of booleans indicating completion status
-func (md *Markdown) TaskList(items []string, checked []bool) {
-    if len(items) == 0 {
-        return // Skip empty task lists
+// - code: The emoji shortcode, with or without surrounding colons
+//
+// Returns:
+// - string: The ":code:" shortcode, or the Unicode emoji if enabled
+func (md *Markdown) EmojiInline(code string) string {
+    code = strings.Trim(code, ":")
+    if code == "" {
+        return ""
     }
-    for i, item := range items {
-        if item == "" {
-            continue // Skip empty items
+    if !md.IsValidEmoji(code) {
+        if md.strict {
+            md.check(fmt.Errorf("markdown: unknown emoji shortcode %q", code))
+        } else {
+            md.lock()
+            md.unknownEmoji++
+            md.unlock()
         }
-        check := " "
-        if i < len(checked) && checked[i] {
-            check = "x"
+    }
+    return md.emoji(code)
+}
+
+// emoji renders a shortcode as Unicode if enabled and known, or as ":code:".
+func (md *Markdown) emoji(code string) string {
+    if md.unicodeEmoji {
+        if emoji, ok := emojiShortcodes[code]; ok {
+            return emoji
         }
-        md.content.WriteString(fmt.Sprintf("- [%s] %s\n", check, item))
     }
-    md.content.WriteString("\n")
+    return ":" + code + ":"
 }
 
 // MermaidDiagram adds a Mermaid diagram to the Markdown content.
@@ -385,7 +4210,29 @@ func (md *Markdown) MermaidDiagram(diagram string) {
     if diagram == "" {
         return // Skip empty diagrams
     }
-    md.content.WriteString(fmt.Sprintf("```mermaid\n%s\n```\n\n", diagram))
+    md.FencedBlock("mermaid", diagram)
+}
+
+// PlantUML adds a PlantUML diagram to the Markdown content.
+//
+// Parameters:
+// - diagram: The PlantUML syntax for the diagram
+func (md *Markdown) PlantUML(diagram string) {
+    if diagram == "" {
+        return // Skip empty diagrams
+    }
+    md.FencedBlock("plantuml", diagram)
+}
+
+// Graphviz adds a Graphviz diagram in the DOT language to the Markdown content.
+//
+// Parameters:
+// - dot: The DOT source of the graph
+func (md *Markdown) Graphviz(dot string) {
+    if dot == "" {
+        return // Skip empty diagrams
+    }
+    md.FencedBlock("dot", dot)
 }
 
 // MathBlock inserts a block math equation compatible with KaTeX or MathJax.
@@ -396,7 +4243,7 @@ func (md *Markdown) MathBlock(equation string) {
     if equation == "" {
         return // Skip empty equations
     }
-    md.content.WriteString(fmt.Sprintf("$$\n%s\n$$\n\n", equation))
+    md.write(fmt.Sprintf("$$\n%s\n$$\n\n", equation))
 }
 
 // Underline applies an underline style to text using HTML.
@@ -432,7 +4279,80 @@ func (md *Markdown) Superscript(text string) string {
     return fmt.Sprintf("<sup>%s</sup>", text)
 }
 
-// ColorText adds color to the text if color support is enabled.
+// NBSP returns a non-breaking space as an HTML entity.
+//
+// Returns:
+// - string: The &nbsp; entity
+func (md *Markdown) NBSP() string {
+    return "&nbsp;"
+}
+
+// SoftHyphen returns a soft hyphen as an HTML entity, marking a position where
+// a long word may be hyphenated when it has to wrap.
+//
+// Returns:
+// - string: The &shy; entity
+func (md *Markdown) SoftHyphen() string {
+    return "&shy;"
+}
+
+// NoBreak joins the words of text with non-breaking spaces so that a phrase,
+// e.g. a product name, is never wrapped across lines.
+//
+// Parameters:
+// - text: The phrase to keep on one line
+//
+// Returns:
+// - string: The words of text joined by &nbsp; entities
+func (md *Markdown) NoBreak(text string) string {
+    return strings.Join(strings.Fields(text), md.NBSP())
+}
+
+// Span creates a Pandoc bracketed span with attributes, e.g.
+// [text]{#id .class key="val"}. The "id" key becomes the identifier and the
+// "class" key holds space-separated class names; all other keys are rendered
+// as key-value attributes in alphabetical order.
+//
+// Parameters:
+// - text: The text of the span
+// - attrs: The attributes of the span
+//
+// Returns:
+// - string: The span in Pandoc syntax
+func (md *Markdown) Span(text string, attrs map[string]string) string {
+    return "[" + text + "]" + pandocAttributes(attrs)
+}
+
+// pandocAttributes formats attributes as a Pandoc attribute block in a stable
+// order: the identifier, then the classes, then the remaining keys sorted
+// alphabetically with escaped, quoted values.
+func pandocAttributes(attrs map[string]string) string {
+    var parts []string
+    if id := attrs["id"]; id != "" {
+        parts = append(parts, "#"+id)
+    }
+    for _, class := range strings.Fields(attrs["class"]) {
+        parts = append(parts, "."+class)
+    }
+    keys := make([]string, 0, len(attrs))
+    for key := range attrs {
+        if key != "id" && key != "class" && key != "" {
+            keys = append(keys, key)
+        }
+    }
+    sort.Strings(keys)
+    for _, key := range keys {
+        value := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(attrs[key])
+        parts = append(parts, fmt.Sprintf("%s=\"%s\"", key, value))
+    }
+    return "{" + strings.Join(parts, " ") + "}"
+}
+
+// ColorText adds color to the text if color support is enabled. Colors may be
+// named CSS colors, hex colors of the form #rgb or #rrggbb, or rgb(r, g, b)
+// colors, or names from the palette set with SetPalette. Text with an invalid
+// color is returned unstyled; in strict mode the invalid color is also recorded
+// as an error.
 //
 // Parameters:
 // - text: The text to colorize
@@ -441,24 +4361,477 @@ func (md *Markdown) Superscript(text string) string {
 // Returns:
 // - string: The text with color applied, or plain if color support is disabled
 func (md *Markdown) ColorText(text, color string) string {
-    if md.useColor {
-        return fmt.Sprintf("<span style=\"color:%s\">%s</span>", color, text)
+    if !md.useColor {
+        return text
     }
-    return text
+    color = strings.TrimSpace(color)
+    if mapped, ok := md.palette[color]; ok {
+        color = mapped
+    }
+    if !validColor(color) {
+        md.check(fmt.Errorf("markdown: invalid color %q", color))
+        return text
+    }
+    return fmt.Sprintf("<span style=\"color:%s\">%s</span>", color, text)
+}
+
+// SetPalette defines logical color names, such as "primary" or "warning", that
+// ColorText resolves to the mapped CSS colors. This keeps the colors of a
+// document consistent and easy to change. Names not in the palette are treated
+// as literal colors.
+//
+// Parameters:
+// - palette: The logical color names mapped to CSS colors, nil to clear
+func (md *Markdown) SetPalette(palette map[string]string) {
+    md.palette = make(map[string]string, len(palette))
+    for name, color := range palette {
+        md.palette[name] = color
+    }
+}
+
+// ColorTextRGB adds color to the text from its red, green, and blue components.
+//
+// Parameters:
+// - text: The text to colorize
+// - r, g, b: The color components
+//
+// Returns:
+// - string: The text with color applied, or plain if color support is disabled
+func (md *Markdown) ColorTextRGB(text string, r, g, b uint8) string {
+    return md.ColorText(text, fmt.Sprintf("#%02x%02x%02x", r, g, b))
 }
 
-// ToHTML converts the Markdown content to a basic HTML structure.
+// ToHTML converts the Markdown content to a basic HTML structure. Fenced code
+// blocks become <pre><code> elements whose class names the language of the
+// block, e.g. "language-go", so that client-side highlighters such as Prism or
+// highlight.js pick them up.
 //
 // Returns:
 // - string: The content wrapped in basic HTML tags with line breaks
 func (md *Markdown) ToHTML() string {
-    return "<html>" + strings.ReplaceAll(md.GetContent(), "\n", "<br>") + "</html>"
+    var out, code strings.Builder
+    var fences fenceState
+    lang := ""
+    for _, line := range strings.SplitAfter(md.GetContent(), "\n") {
+        switch fences.scan(line) {
+        case openingFence:
+            lang = ""
+            if fields := strings.Fields(fences.info); len(fields) > 0 {
+                lang = strings.TrimPrefix(fields[0], "{")
+            }
+        case codeLine:
+            code.WriteString(line)
+        case closingFence:
+            out.WriteString(htmlCodeBlock(lang, code.String()))
+            code.Reset()
+        default:
+            out.WriteString(strings.ReplaceAll(line, "\n", "<br>"))
+        }
+    }
+    if fences.fence != "" {
+        out.WriteString(htmlCodeBlock(lang, code.String())) // Unclosed block
+    }
+    return "<html>" + out.String() + "</html>"
+}
+
+// htmlCodeBlock renders code as an HTML <pre><code> element with a language
+// class if lang is set.
+func htmlCodeBlock(lang, code string) string {
+    class := ""
+    if lang != "" {
+        class = " class=\"language-" + html.EscapeString(lang) + "\""
+    }
+    return "<pre><code" + class + ">" + html.EscapeString(code) + "</code></pre>"
+}
+
+// allowedSchemes lists the URL schemes accepted in strict URL mode. The empty
+// scheme stands for relative URLs.
+var allowedSchemes = map[string]bool{"": true, "http": true, "https": true, "mailto": true, "tel": true}
+
+// normalizeURL percent-encodes spaces and other disallowed characters in raw and
+// checks its scheme against allowedSchemes. On error the trimmed input is returned
+// unchanged together with a description of the problem.
+func normalizeURL(raw string) (string, error) {
+    raw = strings.TrimSpace(raw)
+    if raw == "" {
+        return "", fmt.Errorf("markdown: URL is empty")
+    }
+    u, err := url.Parse(raw)
+    if err != nil {
+        return raw, fmt.Errorf("markdown: invalid URL %q", raw)
+    }
+    u.RawQuery = escapeQuery(u.RawQuery)
+    if !allowedSchemes[strings.ToLower(u.Scheme)] {
+        return u.String(), fmt.Errorf("markdown: URL scheme %q is not allowed", u.Scheme)
+    }
+    return u.String(), nil
+}
+
+// escapeQuery percent-encodes characters that are not allowed in a URL query
+// while keeping existing escapes and delimiters intact.
+func escapeQuery(query string) string {
+    var b strings.Builder
+    for i := 0; i < len(query); i++ {
+        c := query[i]
+        if c <= ' ' || c >= 0x7f || strings.IndexByte("\"<>\\^`{|}", c) >= 0 {
+            b.WriteString(fmt.Sprintf("%%%02X", c))
+        } else {
+            b.WriteByte(c)
+        }
+    }
+    return b.String()
+}
+
+// SetBaseURL sets the base URL against which relative URLs of links and images
+// are resolved, for documents that will be published at a known location.
+// Absolute URLs and fragments such as "#usage" are kept as they are. An invalid
+// base is recorded as an error in strict mode and otherwise ignored.
+//
+// Parameters:
+// - base: The base URL, e.g. "https://example.com/docs/", or "" to disable
+func (md *Markdown) SetBaseURL(base string) {
+    if base == "" {
+        md.baseURL = nil
+        return
+    }
+    u, err := url.Parse(base)
+    if err != nil || !u.IsAbs() {
+        md.check(fmt.Errorf("markdown: invalid base URL %q", base))
+        return
+    }
+    md.baseURL = u
+}
+
+// resolveURL normalizes raw for use in a link or image and resolves it against
+// the base URL. It fails for empty URLs and, when strict URL mode is enabled,
+// for URLs that do not pass validation.
+func (md *Markdown) resolveURL(raw string) (string, error) {
+    normalized, err := normalizeURL(raw)
+    if err != nil && (md.strictURLs || normalized == "") {
+        return "", err
+    }
+    if err == nil && md.baseURL != nil && !strings.HasPrefix(normalized, "#") {
+        if u, err := url.Parse(normalized); err == nil && !u.IsAbs() {
+            normalized = md.baseURL.ResolveReference(u).String()
+        }
+    }
+    return normalized, nil
+}
+
+// validateHeading checks that a heading has text.
+func validateHeading(text string) error {
+    if text == "" {
+        return fmt.Errorf("markdown: heading text is empty")
+    }
+    return nil
+}
+
+// validateTable checks that a table has headers and rows, and that every row
+// has as many cells as there are headers.
+func validateTable(headers []string, rows [][]string) error {
+    if len(headers) == 0 {
+        return fmt.Errorf("markdown: table has no headers")
+    }
+    if len(rows) == 0 {
+        return fmt.Errorf("markdown: table has no rows")
+    }
+    for i, row := range rows {
+        if len(row) != len(headers) {
+            return fmt.Errorf("markdown: table row %d has %d cells, expected %d", i+1, len(row), len(headers))
+        }
+    }
+    return nil
 }
 
-// GetContent retrieves the current Markdown content as a string.
+// GetContent retrieves the current Markdown content as a string. In thread-safe
+// mode it takes the document lock.
 //
 // Returns:
 // - string: The accumulated Markdown content
 func (md *Markdown) GetContent() string {
-    return md.content.String()
+    md.lock()
+    content := md.content.String()
+    md.unlock()
+    if md.extractLinks {
+        return extractInlineLinks(content)
+    }
+    return content
+}
+
+// Normalize tidies the accumulated content: runs of blank lines collapse into
+// a single blank line and trailing whitespace is trimmed from each line.
+// Fenced code blocks are left as they are. The content keeps the blank line
+// that ends the last block, so blocks added afterwards stay separate; use
+// Finalize for output that ends with exactly one newline. Content already
+// streamed to a writer is not affected. Marks returned by Snapshot no longer
+// point into the rewritten content, so they become invalid.
+func (md *Markdown) Normalize() {
+    md.lock()
+    defer md.unlock()
+    content := normalize(md.content.String())
+    if content != "" {
+        content += strings.Repeat("\n", md.blockSpacing) // Block separator
+    }
+    md.content.Reset()
+    md.content.WriteString(content)
+    md.snapshots = nil
+}
+
+// Finalize returns the content tidied like Normalize, with trailing whitespace
+// stripped, runs of blank lines collapsed, and exactly one final newline, which
+// keeps linters such as markdownlint quiet. Unlike Normalize it leaves the
+// document unchanged, so content can still be appended afterwards.
+//
+// Returns:
+// - string: The tidied content
+func (md *Markdown) Finalize() string {
+    return normalize(md.GetContent())
+}
+
+// normalize implements Normalize on a string.
+func normalize(text string) string {
+    var out strings.Builder
+    var fences fenceState
+    blank := false // A blank line is pending
+    for _, line := range strings.Split(text, "\n") {
+        if fences.fence != "" {
+            fences.scan(line)
+            out.WriteString(line + "\n")
+            continue
+        }
+        line = strings.TrimRight(line, " \t\r")
+        if line == "" {
+            blank = out.Len() > 0 // Drop leading blank lines
+            continue
+        }
+        if blank {
+            out.WriteString("\n")
+            blank = false
+        }
+        fences.scan(line)
+        out.WriteString(line + "\n")
+    }
+    return out.String()
+}
+
+// Bytes returns the Markdown content as a byte slice. The slice is a copy and
+// does not alias the internal buffer, so it may be modified freely.
+//
+// Returns:
+// - []byte: The accumulated Markdown content
+func (md *Markdown) Bytes() []byte {
+    return []byte(md.GetContent())
+}
+
+// WriteTo writes the Markdown content to w without an intermediate copy when w
+// implements io.StringWriter. It implements io.WriterTo.
+//
+// Parameters:
+// - w: The destination for the content
+//
+// Returns:
+// - int64: The number of bytes written
+// - error: The error returned by w, or nil
+func (md *Markdown) WriteTo(w io.Writer) (int64, error) {
+    n, err := io.WriteString(w, md.GetContent())
+    return int64(n), err
+}
+
+// Reader returns a reader over the Markdown content, e.g. to pass the document
+// as an HTTP request body. The reader holds a snapshot of the content at the
+// time of the call; content added later is not visible through it.
+//
+// Returns:
+// - io.Reader: A strings.Reader over the current content
+func (md *Markdown) Reader() io.Reader {
+    return strings.NewReader(md.GetContent())
+}
+
+// Len returns the length of the accumulated content in bytes without copying
+// it. Link extraction, which GetContent applies, is not taken into account.
+//
+// Returns:
+// - int: The number of bytes written so far
+func (md *Markdown) Len() int {
+    md.lock()
+    defer md.unlock()
+    return md.content.Len()
+}
+
+// inlineLinkPattern matches inline links and images of the form [text](url).
+var inlineLinkPattern = regexp.MustCompile(`(!?)\[([^\[\]]*)\]\(([^()\s]+)\)`)
+
+// ToReferenceStyle returns the content with inline links converted to numbered
+// reference links, e.g. "[text][1]", whose definitions are appended at the end.
+// Links to the same URL share one definition; images and code are left as they
+// are. Unlike SetExtractInlineLinks it does not change what GetContent returns.
+//
+// Returns:
+// - string: The content with reference-style links
+func (md *Markdown) ToReferenceStyle() string {
+    md.lock()
+    content := md.content.String()
+    md.unlock()
+    return extractInlineLinks(content)
+}
+
+// extractInlineLinks replaces inline links outside of code with numbered
+// reference links and appends the definitions to the end of the text.
+// Images are left untouched.
+func extractInlineLinks(text string) string {
+    var urls []string
+    numbers := map[string]int{}
+    text = mapOutsideCode(text, func(prose string) string {
+        return inlineLinkPattern.ReplaceAllStringFunc(prose, func(link string) string {
+            match := inlineLinkPattern.FindStringSubmatch(link)
+            if match[1] == "!" {
+                return link // Keep images inline
+            }
+            n, exists := numbers[match[3]]
+            if !exists {
+                urls = append(urls, match[3])
+                n = len(urls)
+                numbers[match[3]] = n
+            }
+            return fmt.Sprintf("[%s][%d]", match[2], n)
+        })
+    })
+    if len(urls) == 0 {
+        return text
+    }
+    var b strings.Builder
+    b.WriteString(text)
+    switch {
+    case text == "" || strings.HasSuffix(text, "\n\n"):
+    case strings.HasSuffix(text, "\n"):
+        b.WriteString("\n")
+    default:
+        b.WriteString("\n\n")
+    }
+    for i, url := range urls {
+        b.WriteString(fmt.Sprintf("[%d]: %s\n", i+1, url))
+    }
+    return b.String()
+}
+
+// mapOutsideCode applies fn to every part of text that lies outside fenced
+// code blocks and inline code spans, leaving the code itself untouched.
+func mapOutsideCode(text string, fn func(string) string) string {
+    var out, prose strings.Builder
+    flushProse := func() {
+        out.WriteString(mapOutsideCodeSpans(prose.String(), fn))
+        prose.Reset()
+    }
+    var fences fenceState
+    for _, line := range strings.SplitAfter(text, "\n") {
+        switch fences.scan(line) {
+        case proseLine:
+            prose.WriteString(line)
+        case openingFence:
+            flushProse()
+            out.WriteString(line)
+        default:
+            out.WriteString(line)
+        }
+    }
+    flushProse()
+    return out.String()
+}
+
+// fenceRun returns the opening fence (three or more backticks or tildes) that
+// starts line, or an empty string if line does not open a fenced block. The
+// info string of a backtick fence must not contain backticks.
+func fenceRun(line string) string {
+    if line == "" || (line[0] != '`' && line[0] != '~') {
+        return ""
+    }
+    n := 0
+    for n < len(line) && line[n] == line[0] {
+        n++
+    }
+    if n < 3 || (line[0] == '`' && strings.Contains(line[n:], "`")) {
+        return ""
+    }
+    return line[:n]
+}
+
+// Kinds of lines reported by fenceState.scan.
+const (
+    proseLine    = iota // A line outside fenced code blocks
+    openingFence        // The opening fence of a code block
+    codeLine            // A line inside a code block
+    closingFence        // The closing fence of a code block
+)
+
+// fenceState tracks fenced code blocks while text is scanned line by line. A
+// block is closed by a fence of the same character that is at least as long
+// as the opening fence; an unclosed block extends to the end of the text.
+type fenceState struct {
+    fence string // The opening fence of the current block, or "" outside code
+    info  string // The info string of the current block, e.g. a language
+}
+
+// scan classifies line, with or without its line ending, and advances the
+// state past it.
+func (f *fenceState) scan(line string) int {
+    trimmed := strings.TrimLeft(strings.TrimSuffix(line, "\n"), " ")
+    if f.fence != "" {
+        if strings.HasPrefix(trimmed, f.fence) && strings.Trim(strings.TrimSpace(trimmed), f.fence[:1]) == "" {
+            f.fence, f.info = "", ""
+            return closingFence
+        }
+        return codeLine
+    }
+    if fence := fenceRun(trimmed); fence != "" {
+        f.fence, f.info = fence, strings.TrimSpace(trimmed[len(fence):])
+        return openingFence
+    }
+    return proseLine
+}
+
+// mapOutsideCodeSpans applies fn to the parts of text outside inline code spans.
+func mapOutsideCodeSpans(text string, fn func(string) string) string {
+    var out strings.Builder
+    start := 0 // Start of the pending prose
+    for i := 0; i < len(text); {
+        if text[i] != '`' {
+            i++
+            continue
+        }
+        n := 0
+        for i+n < len(text) && text[i+n] == '`' {
+            n++
+        }
+        end := closingBackticks(text, i+n, n)
+        if end < 0 {
+            i += n // Unmatched backticks are literal text
+            continue
+        }
+        out.WriteString(fn(text[start:i]))
+        out.WriteString(text[i : end+n])
+        i = end + n
+        start = i
+    }
+    out.WriteString(fn(text[start:]))
+    return out.String()
+}
+
+// closingBackticks finds the index of the next run of exactly n backticks in
+// text starting at from, or -1 if there is none.
+func closingBackticks(text string, from, n int) int {
+    for i := from; i < len(text); {
+        if text[i] != '`' {
+            i++
+            continue
+        }
+        run := 0
+        for i+run < len(text) && text[i+run] == '`' {
+            run++
+        }
+        if run == n {
+            return i
+        }
+        i += run
+    }
+    return -1
 }
@@ -0,0 +1,88 @@
+package markdown
+
+import (
+    "regexp"
+    "strings"
+    "unicode"
+)
+
+// DocStats summarizes the structure of a document, see Stats.
+type DocStats struct {
+    Headings   [6]int // Number of headings per level, Headings[0] counting level 1
+    Paragraphs int
+    CodeBlocks int
+    Links      int
+    Images     int
+    Tables     int
+    Words      int // Words outside code, counting only tokens with letters or digits
+}
+
+var (
+    statsBlockPattern = regexp.MustCompile(`^\s*([-*+>|<]|\d+[.)]\s|\[[^\]]*\]:|:\s|(-\s*){3,}$|(\*\s*){3,}$|(_\s*){3,}$)`)
+    htmlImagePattern  = regexp.MustCompile(`(?i)<img\b`)
+    htmlTablePattern  = regexp.MustCompile(`(?i)<table\b`)
+)
+
+// Stats scans the content and counts its headings, paragraphs, code blocks,
+// links, images, tables, and words, e.g. to audit the structure of a long
+// document. Inline links, images, and HTML images and tables are recognized;
+// links and words inside code are not counted.
+//
+// Returns:
+// - DocStats: The counts for the current content
+func (md *Markdown) Stats() DocStats {
+    var stats DocStats
+    md.lock()
+    content := md.content.String() // Before link extraction, which rewrites links
+    md.unlock()
+    lines := strings.Split(content, "\n")
+    var fences fenceState
+    blockStart := true // The line starts a new block
+    for i, line := range lines {
+        switch fences.scan(line) {
+        case openingFence:
+            stats.CodeBlocks++
+            continue
+        case codeLine:
+            continue
+        case closingFence:
+            blockStart = true
+            continue
+        }
+        trimmed := strings.TrimLeft(line, " ")
+        if strings.TrimSpace(line) == "" {
+            blockStart = true
+            continue
+        }
+        switch m := atxHeadingPattern.FindStringSubmatch(trimmed); {
+        case m != nil:
+            stats.Headings[len(m[1])-1]++
+            blockStart = true
+            continue
+        case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && tableSeparatorPattern.MatchString(lines[i+1]):
+            stats.Tables++
+        case blockStart && !statsBlockPattern.MatchString(line):
+            stats.Paragraphs++
+        }
+        blockStart = false
+    }
+    mapOutsideCode(content, func(text string) string {
+        stats.Tables += len(htmlTablePattern.FindAllString(text, -1))
+        stats.Images += len(htmlImagePattern.FindAllString(text, -1))
+        for _, m := range inlineLinkPattern.FindAllStringSubmatch(text, -1) {
+            if m[1] == "!" {
+                stats.Images++
+            } else {
+                stats.Links++
+            }
+        }
+        text = inlineLinkPattern.ReplaceAllString(text, " $2 ") // Count link text, not targets
+        for _, word := range strings.Fields(text) {
+            if strings.IndexFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+                stats.Words++
+            }
+        }
+        return text
+    })
+    return stats
+}
@@ -0,0 +1,49 @@
+package markdown
+
+import (
+    "io"
+)
+
+// StreamMarkdown writes Markdown blocks directly to an io.Writer as they are
+// added instead of accumulating them in memory. It offers the same methods as
+// Markdown, which it embeds. Footnote definitions are deferred and written
+// when Close is called, so they end up at the bottom of the document.
+//
+// Methods that inspect or rewrite the accumulated content, such as GetContent,
+// see an empty document because nothing is buffered.
+type StreamMarkdown struct {
+    *Markdown
+}
+
+// NewWriter initializes a streaming Markdown document that writes to w.
+//
+// Parameters:
+// - w: The destination for the generated Markdown
+// - flavor: The Markdown flavor to use (StandardMarkdown, GitHubMarkdown, JupyterMarkdown)
+// - useColor: Whether or not to use color in the Markdown output
+//
+// Returns:
+// - *StreamMarkdown: A pointer to the initialized streaming document
+func NewWriter(w io.Writer, flavor int, useColor bool) *StreamMarkdown {
+    md := New(flavor, useColor)
+    md.out = w
+    md.deferFootnotes = true
+    return &StreamMarkdown{Markdown: md}
+}
+
+// Close flushes deferred footnotes, including those allocated by AutoFootnote,
+// to the underlying writer. It does not close the writer itself.
+//
+// Returns:
+// - error: The first error recorded while writing, or nil
+func (sm *StreamMarkdown) Close() error {
+    sm.RenderFootnotes()
+    sm.lock()
+    footnotes := sm.footnotes.String()
+    sm.footnotes.Reset()
+    sm.unlock()
+    if footnotes != "" {
+        sm.write(footnotes)
+    }
+    return sm.Err()
+}
@@ -1,6 +1,13 @@
 package markdown_test
 
 import (
+    "bytes"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
     "testing"
     "github.com/ms1963/markdown"
 )
@@ -137,6 +144,16 @@ func TestEscape(t *testing.T) {
     if escaped != expected {
         t.Errorf("TestEscape failed:\nExpected:\n%s\nGot:\n%s", expected, escaped)
     }
+
+    // Backslashes are escaped once, so C:\dir\*.go renders as given.
+    path := `C:\dir\*.go`
+    escapedPath := `C:\\dir\\\*\.go`
+    compareOutput(t, "TestEscape backslash", escapedPath, md.Escape(path))
+    compareOutput(t, "TestEscape backslash document", "`"+path+"` "+escapedPath, md.EscapeDocument("`"+path+"` "+path))
+    md.KeyValues([]markdown.KV{{Key: "Path", Value: path}})
+    md.SetTemplateEscape(true)
+    md.Template("Path: {{path}}", map[string]string{"path": path})
+    compareOutput(t, "TestEscape backslash blocks", "**Path:** "+escapedPath+"\n\nPath: "+escapedPath+"\n\n", md.GetContent())
 }
 
 func TestCustomDiv(t *testing.T) {
@@ -256,3 +273,1871 @@ func TestComplexMarkdown(t *testing.T) {
     expected := "---\ntitle: \"Complex Document\"\nauthor: \"Jane Doe\"\n---\n\n# Main Title\n\nThis paragraph includes some _italic_ text and **bold** text.\n\n- First item\n- Second item\n\n```go\nfmt.Println(\"Hello, Markdown!\")\n```\n\n![Alt text](https://example.com/image.png)\n\n---\n\n> This is a blockquote.\n\n| Feature | Description |\n|:---|---:|\n| Markdown | Text formatting |\n| GitHub | Markdown flavor |\n\n"
     compareOutput(t, "TestComplexMarkdown", expected, md.GetContent())
 }
+
+func TestFigure(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.Figure("A \"quoted\" cat", "https://example.com/cat.png?a=1&b=2", "Figure caption")
+    expected := "<figure id=\"figure-1\"><img src=\"https://example.com/cat.png?a=1&amp;b=2\" alt=\"A &#34;quoted&#34; cat\"><figcaption>Figure caption</figcaption></figure>\n\n"
+    compareOutput(t, "TestFigure", expected, md.GetContent())
+}
+
+func TestFigureWithoutCaption(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.Figure("Cat", "https://example.com/cat.png", "")
+    md.Figure("Missing", "", "Caption")
+    expected := "<figure><img src=\"https://example.com/cat.png\" alt=\"Cat\"></figure>\n\n"
+    compareOutput(t, "TestFigureWithoutCaption", expected, md.GetContent())
+}
+
+func TestGitLog(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.SetRepoURL("https://github.com/ms1963/markdown/")
+    md.GitLog([]markdown.Commit{
+        {Hash: "0c522fe1a2b3c4d5", Subject: "Initial import", Author: "Jane Doe"},
+        {Hash: "418accf9f8e7d6c5", Subject: "Add Figure", Author: "John Doe"},
+    })
+    expected := "- [`0c522fe`](https://github.com/ms1963/markdown/commit/0c522fe1a2b3c4d5) Initial import (Jane Doe)\n" +
+        "- [`418accf`](https://github.com/ms1963/markdown/commit/418accf9f8e7d6c5) Add Figure (John Doe)\n\n"
+    compareOutput(t, "TestGitLog", expected, md.GetContent())
+}
+
+func TestMailtoLink(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    compareOutput(t, "TestMailtoLink", "[Jane](mailto:jane@example.com)", md.MailtoLink("Jane", "jane@example.com"))
+    compareOutput(t, "TestMailtoLink invalid", "", md.MailtoLink("Jane", "jane.example.com"))
+}
+
+func TestTelLink(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    compareOutput(t, "TestTelLink", "[Call us](tel:+49-89-123-456)", md.TelLink("Call us", "+49 89 123-456"))
+    compareOutput(t, "TestTelLink invalid", "", md.TelLink("Call us", "+49 (89) 123"))
+    compareOutput(t, "TestTelLink no digits", "", md.TelLink("Call us", "+ -"))
+}
+
+func TestExtractInlineLinks(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.SetExtractInlineLinks(true)
+    md.Paragraph("Read the [guide](https://example.com/docs) and the [manual](https://example.com/docs).")
+    md.Paragraph("Code like `[x](https://example.com/code)` stays as is.")
+    expected := "Read the [guide][1] and the [manual][1].\n\n" +
+        "Code like `[x](https://example.com/code)` stays as is.\n\n" +
+        "[1]: https://example.com/docs\n"
+    compareOutput(t, "TestExtractInlineLinks", expected, md.GetContent())
+}
+
+func TestMetrics(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.Metrics([]markdown.Metric{
+        {Label: "Revenue", Value: "$12k", Delta: 4.5},
+        {Label: "Churn", Value: "2%", Delta: -0.5},
+        {Label: "Users", Value: "1,024"},
+    })
+    expected := "| Metric | Value | Change |\n|:---|---:|---:|\n| Revenue | $12k | ▲ 4.5 |\n| Churn | 2% | ▼ 0.5 |\n| Users | 1,024 | 0 |\n\n"
+    compareOutput(t, "TestMetrics", expected, md.GetContent())
+}
+
+func TestLinkNormalizesURL(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    link := md.Link("Docs", "https://example.com/my docs/page 1.html?q=a b")
+    compareOutput(t, "TestLinkNormalizesURL", "[Docs](https://example.com/my%20docs/page%201.html?q=a%20b)", link)
+    md.Image("Logo", "images/company logo.png")
+    compareOutput(t, "TestImageNormalizesURL", "![Logo](images/company%20logo.png)\n\n", md.GetContent())
+}
+
+func TestStrictURLs(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    compareOutput(t, "TestStrictURLs lenient", "[Click](javascript:alert(1))", md.Link("Click", "javascript:alert(1)"))
+    md.SetStrictURLs(true)
+    compareOutput(t, "TestStrictURLs link", "", md.Link("Click", "javascript:alert(1)"))
+    md.Image("Tracker", "javascript:alert(1)")
+    compareOutput(t, "TestStrictURLs image", "", md.GetContent())
+    compareOutput(t, "TestStrictURLs mailto", "[Mail](mailto:jane@example.com)", md.Link("Mail", "mailto:jane@example.com"))
+}
+
+func TestErrorReturningVariants(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+
+    if err := md.HeadingE(1, "", "", ""); err == nil || err.Error() != "markdown: heading text is empty" {
+        t.Errorf("HeadingE returned unexpected error: %v", err)
+    }
+    err := md.TableE([]string{"Name", "Age"}, [][]string{{"John", "30"}, {"Jane"}}, nil)
+    if err == nil || err.Error() != "markdown: table row 2 has 1 cells, expected 2" {
+        t.Errorf("TableE returned unexpected error: %v", err)
+    }
+    if err := md.ImageE("Logo", ""); err == nil || err.Error() != "markdown: URL is empty" {
+        t.Errorf("ImageE returned unexpected error: %v", err)
+    }
+    md.SetStrictURLs(true)
+    if _, err := md.LinkE("Click", "javascript:alert(1)"); err == nil || err.Error() != "markdown: URL scheme \"javascript\" is not allowed" {
+        t.Errorf("LinkE returned unexpected error: %v", err)
+    }
+    compareOutput(t, "TestErrorReturningVariants content", "", md.GetContent())
+
+    if err := md.HeadingE(2, "Valid", "", ""); err != nil {
+        t.Errorf("HeadingE returned unexpected error: %v", err)
+    }
+    compareOutput(t, "TestErrorReturningVariants valid", "## Valid\n\n", md.GetContent())
+}
+
+func TestSidebarNav(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.Heading(1, "Guide", "", "")
+    md.Heading(2, "Getting Started", "", "")
+    md.Heading(3, "Installation", "", "")
+    md.Heading(2, "API", "api-reference", "")
+    nav := markdown.New(markdown.StandardMarkdown, false)
+    md.SidebarNav(2)
+    expected := "# Guide\n\n## Getting Started\n\n### Installation\n\n## API {#api-reference}\n\n" +
+        "- [Guide](#guide)\n  - [Getting Started](#getting-started)\n  - [API](#api-reference)\n\n"
+    compareOutput(t, "TestSidebarNav", expected, md.GetContent())
+
+    nav.SetHTMLOutput(true)
+    nav.Heading(1, "Guide", "", "")
+    nav.Heading(2, "Usage", "", "")
+    nav.Heading(1, "FAQ", "", "")
+    nav.SidebarNav(2)
+    expected = "# Guide\n\n## Usage\n\n# FAQ\n\n" +
+        "<nav>\n<ul>\n<li><a href=\"#guide\">Guide</a>\n<ul>\n<li><a href=\"#usage\">Usage</a></li>\n</ul>\n</li>\n<li><a href=\"#faq\">FAQ</a></li>\n</ul>\n</nav>\n\n"
+    compareOutput(t, "TestSidebarNav HTML", expected, nav.GetContent())
+}
+
+func TestStrictStickyError(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.SetStrict(true)
+    md.Heading(1, "Title", "", "")
+    md.Table([]string{"A", "B"}, [][]string{{"1"}}, nil)
+    md.Paragraph("Skipped while the error is set.")
+    md.Heading(2, "Also skipped", "", "")
+
+    err := md.Err()
+    if err == nil || err.Error() != "markdown: table row 1 has 1 cells, expected 2" {
+        t.Errorf("Err returned unexpected error: %v", err)
+    }
+    if md.HeadingE(2, "Again", "", "") != err {
+        t.Errorf("HeadingE did not return the sticky error")
+    }
+    compareOutput(t, "TestStrictStickyError content", "# Title\n\n", md.GetContent())
+
+    md.ClearErr()
+    md.Paragraph("Written after clearing.")
+    if md.Err() != nil {
+        t.Errorf("Err not cleared: %v", md.Err())
+    }
+    compareOutput(t, "TestStrictStickyError cleared", "# Title\n\nWritten after clearing.\n\n", md.GetContent())
+}
+
+func TestStrictEmptyInput(t *testing.T) {
+    for name, add := range map[string]func(md *markdown.Markdown){
+        "Paragraph":  func(md *markdown.Markdown) { md.Paragraph("") },
+        "List":       func(md *markdown.Markdown) { md.List(nil, false) },
+        "CodeBlock":  func(md *markdown.Markdown) { md.CodeBlock("go", "") },
+        "Blockquote": func(md *markdown.Markdown) { md.Blockquote("") },
+        "Footnote":   func(md *markdown.Markdown) { md.Footnote("", "x") },
+    } {
+        md := markdown.New(markdown.GitHubMarkdown, false)
+        add(md)
+        if md.Err() != nil {
+            t.Errorf("TestStrictEmptyInput: %s recorded an error outside strict mode: %v", name, md.Err())
+        }
+        md.SetStrict(true)
+        add(md)
+        if md.Err() == nil {
+            t.Errorf("TestStrictEmptyInput: %s with empty input recorded no error in strict mode", name)
+        }
+        compareOutput(t, "TestStrictEmptyInput "+name, "", md.GetContent())
+    }
+}
+
+func TestNonStrictIgnoresErrors(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.Heading(1, "", "", "")
+    md.Paragraph("Still written.")
+    if md.Err() != nil {
+        t.Errorf("Err should be nil outside strict mode, got %v", md.Err())
+    }
+    compareOutput(t, "TestNonStrictIgnoresErrors", "Still written.\n\n", md.GetContent())
+}
+
+func TestAlert(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Alert(markdown.AlertWarning, "Back up your data.")
+    expected := "> [!WARNING]\n> Back up your data.\n\n"
+    compareOutput(t, "TestAlert", expected, md.GetContent())
+}
+
+func TestAlertBlock(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.AlertBlock(markdown.AlertNote, func(note *markdown.Markdown) {
+        note.Paragraph("Remember to:")
+        note.List([]string{"Save your work", "Run the tests"}, false)
+    })
+    expected := "> [!NOTE]\n> Remember to:\n>\n> - Save your work\n> - Run the tests\n\n"
+    compareOutput(t, "TestAlertBlock", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false).WithHeadingNumbers(true)
+    md.Heading(2, "Setup", "", "")
+    md.AlertBlock(markdown.AlertTip, func(tip *markdown.Markdown) {
+        tip.Heading(3, "Shortcut", "", "")
+        tip.Paragraph("Use the installer.")
+    })
+    md.Heading(3, "Manual", "", "")
+    expected = "## 1 Setup\n\n> [!TIP]\n> ### 1.1 Shortcut\n>\n> Use the installer.\n\n### 1.2 Manual\n\n"
+    compareOutput(t, "TestAlertBlock numbered heading", expected, md.GetContent())
+}
+
+// Run with -race to detect unsynchronized writes.
+func TestThreadSafeParagraphs(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false).WithThreadSafe(true)
+    var wg sync.WaitGroup
+    for i := 0; i < 50; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            md.Paragraph("Concurrent paragraph.")
+            _ = md.GetContent()
+        }()
+    }
+    wg.Wait()
+    expected := strings.Repeat("Concurrent paragraph.\n\n", 50)
+    compareOutput(t, "TestThreadSafeParagraphs", expected, md.GetContent())
+}
+
+func TestVersionComparison(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.VersionComparison("v1.0.0", "v1.0.1", "https://github.com/ms1963/markdown/")
+    md.VersionComparison("", "v1.0.1", "https://github.com/ms1963/markdown")
+    expected := "Full changelog: [v1.0.0...v1.0.1](https://github.com/ms1963/markdown/compare/v1.0.0...v1.0.1)\n\n"
+    compareOutput(t, "TestVersionComparison", expected, md.GetContent())
+}
+
+func TestNewWriterStreamsBlocks(t *testing.T) {
+    var buf bytes.Buffer
+    sm := markdown.NewWriter(&buf, markdown.StandardMarkdown, false)
+
+    sm.Heading(1, "Report", "", "")
+    compareOutput(t, "TestNewWriter heading", "# Report\n\n", buf.String())
+
+    sm.Footnote("1", "Source data.")
+    sm.Paragraph("See the data.")
+    compareOutput(t, "TestNewWriter paragraph", "# Report\n\nSee the data.\n\n", buf.String())
+
+    if err := sm.Close(); err != nil {
+        t.Fatalf("Close returned unexpected error: %v", err)
+    }
+    expected := "# Report\n\nSee the data.\n\n[1]: Source data. [Return to text](#fn-1-back)\n"
+    compareOutput(t, "TestNewWriter close", expected, buf.String())
+    compareOutput(t, "TestNewWriter content", "", sm.GetContent())
+}
+
+func TestWikiPage(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.WikiPage("Installation", []markdown.Link{
+        {Text: "Home", URL: "Home"},
+        {Text: "Installation", URL: "Installation"},
+        {Text: "Getting Started", URL: "Getting Started"},
+    })
+    md.Paragraph("Run `go get github.com/ms1963/markdown`.")
+    compareOutput(t, "TestWikiPage page", "# Installation\n\nRun `go get github.com/ms1963/markdown`.\n\n", md.GetContent())
+    expected := "- [Home](Home)\n- [Installation](Installation)\n- [Getting Started](Getting%20Started)\n"
+    compareOutput(t, "TestWikiPage sidebar", expected, md.WikiSidebar())
+}
+
+func TestCodeBlockWithOptions(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.CodeBlockWithOptions("go", "a := 1\nb := 2\nc := 3\nd := 4", []int{4, 1, 3}, false)
+    md.CodeBlockWithOptions("go", "x := 1", []int{1, 2, 3, 5, 7, 8}, true)
+    md.CodeBlockWithOptions("go", "y := 2", nil, true)
+    md.CodeBlockWithOptions("go", "z := 3", []int{0}, false)
+    expected := "```go {1,3-4}\na := 1\nb := 2\nc := 3\nd := 4\n```\n\n" +
+        "```go {1-3,5,7-8} {.line-numbers}\nx := 1\n```\n\n" +
+        "```go {.line-numbers}\ny := 2\n```\n\n"
+    compareOutput(t, "TestCodeBlockWithOptions", expected, md.GetContent())
+}
+
+func TestTruthTable(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.TruthTable([]string{"A", "B"}, func(in []bool) bool { return in[0] && in[1] })
+    expected := "| A | B | Result |\n|:---:|:---:|:---:|\n| F | F | F |\n| F | T | F |\n| T | F | F |\n| T | T | T |\n\n"
+    compareOutput(t, "TestTruthTable", expected, md.GetContent())
+}
+
+func TestConversionTable(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.SetPrecision(3)
+    md.ConversionTable("Length", []markdown.Conversion{
+        {From: "inch", To: "cm", Factor: 2.54},
+        {From: "mile", To: "km", Factor: 1.609344},
+    })
+    expected := "**Length**\n\n| From | To | Factor |\n|:---|:---|---:|\n| inch | cm | 2.540 |\n| mile | km | 1.609 |\n\n"
+    compareOutput(t, "TestConversionTable", expected, md.GetContent())
+}
+
+func TestCodeBlockFenceLength(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.CodeBlock("markdown", "````go\nfmt.Println()\n````")
+    expected := "`````markdown\n````go\nfmt.Println()\n````\n`````\n\n"
+    compareOutput(t, "TestCodeBlockFenceLength", expected, md.GetContent())
+}
+
+func TestCodeBlockTildeFences(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.SetTildeFences(true)
+    md.CodeBlock("sh", "echo ~~~~")
+    md.CodeBlock("go", "x := 1")
+    expected := "~~~~~sh\necho ~~~~\n~~~~~\n\n~~~go\nx := 1\n~~~\n\n"
+    compareOutput(t, "TestCodeBlockTildeFences", expected, md.GetContent())
+}
+
+func TestGlossarySectioned(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.GlossarySectioned([]markdown.GlossaryEntry{
+        {Term: "Markdown", Definition: "A lightweight markup language."},
+        {Term: "Anchor", Definition: "A link target within a page."},
+        {Term: "Mermaid", Definition: "A diagram syntax."},
+    })
+    expected := "[A](#a) · [M](#m)\n\n" +
+        "### A\n\nAnchor\n: A link target within a page.\n\n" +
+        "### M\n\nMarkdown\n: A lightweight markup language.\n\nMermaid\n: A diagram syntax.\n\n"
+    compareOutput(t, "TestGlossarySectioned", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Heading(3, "A", "", "")
+    md.GlossarySectioned([]markdown.GlossaryEntry{
+        {Term: "Anchor", Definition: "A link target."},
+        {Term: "404", Definition: "Not found."},
+        {Term: "@mention", Definition: "A user reference."},
+    })
+    expected = "### A\n\n[0–9](#09) · [Other](#other) · [A](#a-1)\n\n" +
+        "### 0–9\n\n404\n: Not found.\n\n" +
+        "### Other\n\n@mention\n: A user reference.\n\n" +
+        "### A\n\nAnchor\n: A link target.\n\n"
+    compareOutput(t, "TestGlossarySectioned other groups", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false).WithHeadingNumbers(true)
+    md.GlossarySectioned([]markdown.GlossaryEntry{{Term: "Anchor", Definition: "A link target."}})
+    expected = "[A](#1-a)\n\n### 1 A\n\nAnchor\n: A link target.\n\n"
+    compareOutput(t, "TestGlossarySectioned numbered", expected, md.GetContent())
+}
+
+func TestCode(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    compareOutput(t, "TestCode plain", "`fmt.Println`", md.Code("fmt.Println"))
+    compareOutput(t, "TestCode backticks", "``a`b``", md.Code("a`b"))
+    compareOutput(t, "TestCode double backticks", "``` `a``b` ```", md.Code("`a``b`"))
+    compareOutput(t, "TestCode spaces", "`  x  `", md.Code(" x "))
+    compareOutput(t, "TestCode empty", "", md.Code(""))
+    compareOutput(t, "TestCode formatting", "**``a`b``**", md.ApplyFormatting("a`b", "bold", "code"))
+}
+
+func TestCodeBlockFromFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "hello.go")
+    if err := os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    md := markdown.New(markdown.StandardMarkdown, false)
+    if err := md.CodeBlockFromFile(path, ""); err != nil {
+        t.Fatalf("CodeBlockFromFile returned unexpected error: %v", err)
+    }
+    expected := "```go\npackage main\n\nfunc main() {}\n```\n\n"
+    compareOutput(t, "TestCodeBlockFromFile", expected, md.GetContent())
+
+    if err := md.CodeBlockFromFile(filepath.Join(t.TempDir(), "missing.go"), ""); err == nil {
+        t.Errorf("CodeBlockFromFile should fail for a missing file")
+    }
+}
+
+func TestDiffBlock(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.DiffBlock([]string{"version = 2", "debug = false"}, []string{"version = 1"})
+    md.DiffBlock(nil, nil)
+    expected := "```diff\n-version = 1\n+version = 2\n+debug = false\n```\n\n"
+    compareOutput(t, "TestDiffBlock", expected, md.GetContent())
+}
+
+func TestPlantUML(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.PlantUML("@startuml\nAlice -> Bob: Hello\n@enduml")
+    expected := "```plantuml\n@startuml\nAlice -> Bob: Hello\n@enduml\n```\n\n"
+    compareOutput(t, "TestPlantUML", expected, md.GetContent())
+}
+
+func TestGraphviz(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.Graphviz("digraph { A -> B }")
+    md.Graphviz("")
+    expected := "```dot\ndigraph { A -> B }\n```\n\n"
+    compareOutput(t, "TestGraphviz", expected, md.GetContent())
+}
+
+func TestFencedBlock(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.FencedBlock("vega-lite", `{"mark": "bar"}`)
+    md.FencedBlock("vega-lite", "")
+    expected := "```vega-lite\n{\"mark\": \"bar\"}\n```\n\n"
+    compareOutput(t, "TestFencedBlock", expected, md.GetContent())
+}
+
+func TestHTMLBlock(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.Footnote("1", "Note.")
+    md.HTMLBlock("<div class=\"note\">\n  <em>Raw</em> & unescaped\n</div>")
+    md.HTMLBlock("")
+    expected := "[1]: Note. [Return to text](#fn-1-back)\n\n<div class=\"note\">\n  <em>Raw</em> & unescaped\n</div>\n\n"
+    compareOutput(t, "TestHTMLBlock", expected, md.GetContent())
+}
+
+func TestHTMLTable(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.HTMLTable(
+        []markdown.TableCell{{Text: "Name", ColSpan: 2}, {Text: "Age"}},
+        [][]markdown.TableCell{
+            {{Text: "John"}, {Text: "Doe"}, {Text: "30", RowSpan: 2}},
+            {{Text: "Jane"}, {Text: "<Doe>"}},
+        },
+    )
+    expected := "<table>\n<thead>\n<tr><th colspan=\"2\">Name</th><th>Age</th></tr>\n</thead>\n<tbody>\n" +
+        "<tr><td>John</td><td>Doe</td><td rowspan=\"2\">30</td></tr>\n" +
+        "<tr><td>Jane</td><td>&lt;Doe&gt;</td></tr>\n</tbody>\n</table>\n\n"
+    compareOutput(t, "TestHTMLTable", expected, md.GetContent())
+}
+
+func TestTableFooter(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.NewTable("Item", "Qty", "Price").
+        SetAlign("left", "right", "right").
+        AddRow("Apples", "3", "1.50").
+        AddRow("Pears", "2", "1.20").
+        SetFooter("Total", "5", "2.70", "ignored").
+        Render()
+    expected := "| Item | Qty | Price |\n|:---|---:|---:|\n| Apples | 3 | 1.50 |\n| Pears | 2 | 1.20 |\n| **Total** | **5** | **2.70** |\n\n"
+    compareOutput(t, "TestTableFooter", expected, md.GetContent())
+
+    html := markdown.New(markdown.GitHubMarkdown, false)
+    html.NewTable("Item", "Qty").AddRow("Apples", "3").SetFooter("Total").RenderHTML()
+    expected = "<table>\n<thead>\n<tr><th>Item</th><th>Qty</th></tr>\n</thead>\n<tbody>\n<tr><td>Apples</td><td>3</td></tr>\n</tbody>\n" +
+        "<tfoot>\n<tr><td>Total</td><td></td></tr>\n</tfoot>\n</table>\n\n"
+    compareOutput(t, "TestTableFooter HTML", expected, html.GetContent())
+}
+
+func TestSpan(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    span := md.Span("Warning", map[string]string{"class": "alert", "title": `Say "hi"`, "data-level": "2"})
+    compareOutput(t, "TestSpan", `[Warning]{.alert data-level="2" title="Say \"hi\""}`, span)
+}
+
+func TestFencedDiv(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.FencedDiv(map[string]string{"id": "setup", "class": "note wide", "lang": "en"}, "Install Go first.")
+    md.CustomDiv("alert", "Still supported.")
+    expected := "::: {#setup .note .wide lang=\"en\"}\nInstall Go first.\n:::\n\n::: alert\nStill supported.\n:::\n\n"
+    compareOutput(t, "TestFencedDiv", expected, md.GetContent())
+
+    md = markdown.New(markdown.StandardMarkdown, false)
+    md.FencedDiv(nil, "Plain.")
+    md.FencedDiv(map[string]string{}, "Empty.")
+    compareOutput(t, "TestFencedDiv no attributes", ":::\nPlain.\n:::\n\n:::\nEmpty.\n:::\n\n", md.GetContent())
+}
+
+func TestPageBreak(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.PageBreak()
+    md.SetPageBreak(`\newpage`)
+    md.PageBreak()
+    expected := "<div style=\"page-break-after: always;\"></div>\n\n\\newpage\n\n"
+    compareOutput(t, "TestPageBreak", expected, md.GetContent())
+}
+
+func TestHeadingNumbers(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false).WithHeadingNumbers(true)
+    md.Heading(1, "Introduction", "", "")
+    md.Heading(2, "Scope", "", "")
+    md.Heading(2, "Terms", "", "")
+    md.Heading(1, "Usage", "", "")
+    md.Heading(2, "Setup", "", "")
+    md.Heading(4, "Details", "", "")
+    expected := "# 1 Introduction\n\n## 1.1 Scope\n\n## 1.2 Terms\n\n# 2 Usage\n\n## 2.1 Setup\n\n#### 2.1.1.1 Details\n\n"
+    compareOutput(t, "TestHeadingNumbers", expected, md.GetContent())
+}
+
+func TestHeadingOffset(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.SetHeadingOffset(1)
+    md.Heading(1, "Embedded", "", "")
+    md.Heading(6, "Deepest", "", "")
+    expected := "## Embedded\n\n###### Deepest\n\n"
+    compareOutput(t, "TestHeadingOffset", expected, md.GetContent())
+}
+
+func TestBackToTop(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.BackToTop("")
+    md.Heading(1, "My Project", "", "")
+    md.Paragraph("Intro.")
+    md.BackToTop("")
+    md.BackToTop("top")
+    expected := "# My Project\n\nIntro.\n\n[Back to top](#my-project)\n\n[Back to top](#top)\n\n"
+    compareOutput(t, "TestBackToTop", expected, md.GetContent())
+}
+
+func TestAutoBackToTop(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.SetAutoBackToTop(true)
+    md.Heading(1, "My Project", "", "")
+    md.Heading(2, "Install", "", "")
+    md.Paragraph("Run go get.")
+    md.Heading(3, "Details", "", "")
+    md.Heading(2, "Usage", "", "")
+    expected := "# My Project\n\n## Install\n\nRun go get.\n\n### Details\n\n[Back to top](#my-project)\n\n## Usage\n\n"
+    compareOutput(t, "TestAutoBackToTop", expected, md.GetContent())
+}
+
+func TestBadge(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Badge("License: MIT", "https://img.shields.io/badge/License-MIT-yellow.svg", "https://opensource.org/licenses/MIT")
+    expected := "[![License: MIT](https://img.shields.io/badge/License-MIT-yellow.svg)](https://opensource.org/licenses/MIT)\n\n"
+    compareOutput(t, "TestBadge", expected, md.GetContent())
+}
+
+func TestBadgeRow(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.BadgeRow(
+        markdown.Badge{AltText: "Build", ImageURL: "https://img.shields.io/badge/build-passing-green", LinkURL: "https://ci.example.com"},
+        markdown.Badge{AltText: "Broken", LinkURL: "https://example.com"},
+        markdown.Badge{AltText: "Go", ImageURL: "https://img.shields.io/badge/go-1.18-blue"},
+        markdown.Badge{AltText: "Docs", ImageURL: "https://img.shields.io/badge/docs-godoc-blue", LinkURL: "https://pkg.go.dev"},
+    )
+    expected := "[![Build](https://img.shields.io/badge/build-passing-green)](https://ci.example.com) " +
+        "![Go](https://img.shields.io/badge/go-1.18-blue) " +
+        "[![Docs](https://img.shields.io/badge/docs-godoc-blue)](https://pkg.go.dev)\n\n"
+    compareOutput(t, "TestBadgeRow", expected, md.GetContent())
+}
+
+func TestProgressBar(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.ProgressBar(0, 10)
+    md.ProgressBar(50, 10)
+    md.ProgressBar(150, 0)
+    expected := "░░░░░░░░░░ 0%\n\n█████░░░░░ 50%\n\n" + strings.Repeat("█", 20) + " 100%\n\n"
+    compareOutput(t, "TestProgressBar", expected, md.GetContent())
+
+    md = markdown.New(markdown.StandardMarkdown, false)
+    md.SetHTMLOutput(true)
+    md.ProgressBar(50, 10)
+    compareOutput(t, "TestProgressBar HTML", "<progress value=\"50\" max=\"100\">50%</progress>\n\n", md.GetContent())
+}
+
+func TestTaskListWithSummary(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.TaskListWithSummary([]string{"Design", "Build", "Test", "Ship", "Celebrate"}, []bool{true, true, true})
+    expected := "- [x] Design\n- [x] Build\n- [x] Test\n- [ ] Ship\n- [ ] Celebrate\n\n3 of 5 complete (60%)\n\n"
+    compareOutput(t, "TestTaskListWithSummary partial", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.TaskListWithSummary([]string{"Design", "Build"}, []bool{true, true})
+    expected = "- [x] Design\n- [x] Build\n\n2 of 2 complete (100%)\n\n"
+    compareOutput(t, "TestTaskListWithSummary full", expected, md.GetContent())
+}
+
+func TestTaskTree(t *testing.T) {
+    tasks := []markdown.Task{
+        {Text: "Release", Subtasks: []markdown.Task{
+            {Text: "Tag version", Done: true},
+            {Text: "Publish notes", Done: true},
+        }},
+        {Text: "Docs", Done: true, Subtasks: []markdown.Task{
+            {Text: "API reference"},
+        }},
+    }
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.TaskTree(tasks)
+    expected := "- [ ] Release\n  - [x] Tag version\n  - [x] Publish notes\n- [x] Docs\n  - [ ] API reference\n\n"
+    compareOutput(t, "TestTaskTree", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.SetDeriveTaskStatus(true)
+    md.TaskTree(tasks)
+    expected = "- [x] Release\n  - [x] Tag version\n  - [x] Publish notes\n- [ ] Docs\n  - [ ] API reference\n\n"
+    compareOutput(t, "TestTaskTree derived", expected, md.GetContent())
+}
+
+func TestEmoji(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Emoji("smile")
+    md.Emoji(":rocket:")
+    compareOutput(t, "TestEmoji shortcode", ":smile:\n\n:rocket:\n\n", md.GetContent())
+
+    md = markdown.New(markdown.StandardMarkdown, false).WithUnicodeEmoji(true)
+    md.Emoji("smile")
+    md.Emoji("not_an_emoji")
+    compareOutput(t, "TestEmoji unicode", "😄\n\n:not_an_emoji:\n\n", md.GetContent())
+}
+
+func TestEmojiValidation(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    if !md.IsValidEmoji("smile") || !md.IsValidEmoji(":tada:") {
+        t.Errorf("IsValidEmoji rejected a known shortcode")
+    }
+    if md.IsValidEmoji("smille") {
+        t.Errorf("IsValidEmoji accepted an unknown shortcode")
+    }
+    md.Emoji("smille")
+    md.Emoji("smile")
+    if md.UnknownEmojiCount() != 1 {
+        t.Errorf("UnknownEmojiCount = %d, expected 1", md.UnknownEmojiCount())
+    }
+    compareOutput(t, "TestEmojiValidation", ":smille:\n\n:smile:\n\n", md.GetContent())
+
+    if err := md.EmojiE("smille"); err == nil || err.Error() != "markdown: unknown emoji shortcode \"smille\"" {
+        t.Errorf("EmojiE returned unexpected error: %v", err)
+    }
+    md.SetStrict(true)
+    md.Emoji("smille")
+    if md.Err() == nil {
+        t.Errorf("Emoji did not record an error in strict mode")
+    }
+}
+
+func TestEmojiInline(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("Release shipped " + md.EmojiInline("rocket") + " today")
+    compareOutput(t, "TestEmojiInline", "Release shipped :rocket: today\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false).WithUnicodeEmoji(true)
+    md.Heading(2, "Done "+md.EmojiInline(":tada:"), "", "")
+    compareOutput(t, "TestEmojiInline unicode", "## Done 🎉\n\n", md.GetContent())
+
+    if md.EmojiInline("smille") != ":smille:" || md.UnknownEmojiCount() != 1 {
+        t.Errorf("EmojiInline did not count the unknown shortcode")
+    }
+}
+
+func TestNormalize(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("\n\nFirst paragraph   ")
+    md.Paragraph("\n\n\nSecond paragraph\t")
+    md.CodeBlock("go", "a := 1   \n\n\n\nb := 2")
+    md.Paragraph("Last\n\n\n\n")
+    md.Normalize()
+    expected := "First paragraph\n\nSecond paragraph\n\n```go\na := 1   \n\n\n\nb := 2\n```\n\nLast\n\n"
+    compareOutput(t, "TestNormalize", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("a  ")
+    md.Normalize()
+    md.Paragraph("b")
+    md.List([]string{"x"}, false)
+    compareOutput(t, "TestNormalize then append", "a\n\nb\n\n- x\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Normalize()
+    compareOutput(t, "TestNormalize empty", "", md.GetContent())
+}
+
+func TestWrapWidth(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false).WithWrapWidth(40)
+    md.Paragraph("The quick brown fox jumps over the lazy dog and then reads the [project documentation](https://example.com/docs) before running `go test ./...` again.")
+    expected := "The quick brown fox jumps over the lazy\n" +
+        "dog and then reads the\n" +
+        "[project documentation](https://example.com/docs)\n" +
+        "before running `go test ./...` again.\n\n"
+    compareOutput(t, "TestWrapWidth", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false).WithWrapWidth(20)
+    md.Blockquote("Wrapped quotes keep their markers on every line")
+    compareOutput(t, "TestWrapWidth blockquote", "> Wrapped quotes\n> keep their markers\n> on every line\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("Without a width   the text is left alone")
+    compareOutput(t, "TestWrapWidth disabled", "Without a width   the text is left alone\n\n", md.GetContent())
+
+    for _, marker := range []string{"-", "+", "*", "1.", "2)", "#", "##", ">", ">quoted", "---", "==="} {
+        md = markdown.New(markdown.GitHubMarkdown, false).WithWrapWidth(12)
+        md.Paragraph("one two three " + marker + " four")
+        for _, line := range strings.Split(strings.TrimRight(md.GetContent(), "\n"), "\n") {
+            if strings.HasPrefix(line, marker+" ") || line == marker {
+                t.Errorf("TestWrapWidth: line starts with block marker %q:\n%s", marker, md.GetContent())
+            }
+        }
+    }
+    md = markdown.New(markdown.GitHubMarkdown, false).WithWrapWidth(12)
+    md.Paragraph("one two three - four")
+    compareOutput(t, "TestWrapWidth carries the previous word", "one two\nthree - four\n\n", md.GetContent())
+}
+
+func TestSmartTypography(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false).WithSmartTypography(true)
+    md.Paragraph(`She said "it's done" -- pages 3--5 --- see [the docs](https://example.com/a--b) or run ` + "`echo \"x--y\"`.")
+    expected := "She said “it’s done” – pages 3–5 — see [the docs](https://example.com/a--b) or run `echo \"x--y\"`.\n\n"
+    compareOutput(t, "TestSmartTypography", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, true).WithSmartTypography(true)
+    md.Paragraph(`A "red" ` + md.ColorText("word", "red") + ` and <a href="x--y.html" title='t'>link</a>`)
+    expected = "A “red” <span style=\"color:red\">word</span> and <a href=\"x--y.html\" title='t'>link</a>\n\n"
+    compareOutput(t, "TestSmartTypography HTML", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph(`Plain "quotes" -- unchanged`)
+    compareOutput(t, "TestSmartTypography disabled", "Plain \"quotes\" -- unchanged\n\n", md.GetContent())
+}
+
+func TestToANSI(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, true)
+    md.Heading(2, "Status", "", "")
+    md.Paragraph("Build is " + md.ColorText("green", "green") + " and **fast**, see `make` or _docs_.")
+    md.Paragraph(md.ColorText("alert", "#ff1010"))
+    md.CodeBlock("sh", "make **all**")
+    expected := "\x1b[1m\x1b[4mStatus\x1b[0m\n\n" +
+        "Build is \x1b[32mgreen\x1b[0m and \x1b[1mfast\x1b[0m, see \x1b[36mmake\x1b[0m or \x1b[3mdocs\x1b[0m.\n\n" +
+        "\x1b[31malert\x1b[0m\n\n" +
+        "\x1b[36mmake **all**\x1b[0m\n\n"
+    compareOutput(t, "TestToANSI", expected, md.ToANSI())
+
+    md = markdown.New(markdown.GitHubMarkdown, true)
+    md.Paragraph(md.ColorText("failed", "DarkRed") + " " + md.ColorText("hidden", "transparent"))
+    compareOutput(t, "TestToANSI CSS color name", "\x1b[31mfailed\x1b[0m hidden\n\n", md.ToANSI())
+}
+
+func TestColorTextValidation(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, true)
+    compareOutput(t, "TestColorTextValidation hex", "<span style=\"color:#0a0\">ok</span>", md.ColorText("ok", "#0a0"))
+    compareOutput(t, "TestColorTextValidation named", "<span style=\"color:DarkOrange\">ok</span>", md.ColorText("ok", "DarkOrange"))
+    compareOutput(t, "TestColorTextValidation rgb", "<span style=\"color:rgb(10, 20, 30)\">ok</span>", md.ColorText("ok", "rgb(10, 20, 30)"))
+    compareOutput(t, "TestColorTextValidation RGB helper", "<span style=\"color:#ff8000\">ok</span>", md.ColorTextRGB("ok", 255, 128, 0))
+    for _, color := range []string{"notacolor", "#12", "rgb(300, 0, 0)", "red\" onclick=\"x"} {
+        compareOutput(t, "TestColorTextValidation invalid", "plain", md.ColorText("plain", color))
+    }
+    if md.Err() != nil {
+        t.Errorf("ColorText recorded an error outside strict mode: %v", md.Err())
+    }
+    md.SetStrict(true)
+    md.ColorText("plain", "notacolor")
+    if md.Err() == nil || md.Err().Error() != "markdown: invalid color \"notacolor\"" {
+        t.Errorf("ColorText returned unexpected error in strict mode: %v", md.Err())
+    }
+}
+
+func TestSetPalette(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, true)
+    palette := map[string]string{"primary": "#0366d6", "warning": "orange"}
+    md.SetPalette(palette)
+    palette["primary"] = "red" // The document keeps its own copy
+    compareOutput(t, "TestSetPalette primary", "<span style=\"color:#0366d6\">Go</span>", md.ColorText("Go", "primary"))
+    compareOutput(t, "TestSetPalette warning", "<span style=\"color:orange\">Careful</span>", md.ColorText("Careful", "warning"))
+    compareOutput(t, "TestSetPalette literal", "<span style=\"color:green\">Literal</span>", md.ColorText("Literal", "green"))
+}
+
+func TestToHTMLCodeBlock(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("Example:")
+    md.CodeBlock("go", "if a < b && ok {\n}")
+    md.CodeBlock("", "plain")
+    expected := "<html>Example:<br><br>" +
+        "<pre><code class=\"language-go\">if a &lt; b &amp;&amp; ok {\n}\n</code></pre><br>" +
+        "<pre><code>plain\n</code></pre><br></html>"
+    compareOutput(t, "TestToHTMLCodeBlock", expected, md.ToHTML())
+}
+
+func TestConvertFlavor(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.TaskList([]string{"Write docs", "Ship"}, []bool{true, false})
+    md.Paragraph("This is ~~wrong~~ right, unlike `~~code~~`.")
+    md.Table([]string{"Name", "Value"}, [][]string{{"a", "1"}, {"b", "x < y"}}, []string{"left", "right"})
+    md.Alert(markdown.AlertWarning, "Be careful.")
+    md.CodeBlock("md", "- [ ] stays\n~~stays~~")
+    converted, err := md.ConvertFlavor(markdown.StandardMarkdown)
+    if err != nil {
+        t.Fatalf("ConvertFlavor returned error: %v", err)
+    }
+    expected := "- Write docs\n- Ship\n\n" +
+        "This is <del>wrong</del> right, unlike `~~code~~`.\n\n" +
+        "<table>\n<thead>\n<tr><th>Name</th><th>Value</th></tr>\n</thead>\n<tbody>\n" +
+        "<tr><td>a</td><td>1</td></tr>\n<tr><td>b</td><td>x &lt; y</td></tr>\n</tbody>\n</table>\n\n" +
+        "> **Warning**\n> Be careful.\n\n" +
+        "```md\n- [ ] stays\n~~stays~~\n```\n\n"
+    compareOutput(t, "TestConvertFlavor", expected, converted.GetContent())
+    compareOutput(t, "TestConvertFlavor original", md.GetContent(), func() string {
+        same, _ := md.ConvertFlavor(markdown.GitHubMarkdown)
+        return same.GetContent()
+    }())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Table([]string{"Cell"}, [][]string{{md.EscapeTableCell("one\ntwo <b>bold</b> a|b &amp; x < y & z")}}, nil)
+    converted, err = md.ConvertFlavor(markdown.StandardMarkdown)
+    if err != nil {
+        t.Fatalf("ConvertFlavor returned error: %v", err)
+    }
+    expected = "<table>\n<thead>\n<tr><th>Cell</th></tr>\n</thead>\n<tbody>\n" +
+        "<tr><td>one<br>two <b>bold</b> a|b &amp; x &lt; y &amp; z</td></tr>\n</tbody>\n</table>\n\n"
+    compareOutput(t, "TestConvertFlavor inline HTML", expected, converted.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("See the note[^1].")
+    if _, err := md.ConvertFlavor(markdown.StandardMarkdown); err == nil {
+        t.Errorf("ConvertFlavor accepted footnotes for standard Markdown")
+    }
+    if _, err := md.ConvertFlavor(42); err == nil {
+        t.Errorf("ConvertFlavor accepted an unknown flavor")
+    }
+}
+
+func TestIfFlavor(t *testing.T) {
+    build := func(flavor int) string {
+        md := markdown.New(flavor, false)
+        md.Paragraph("Shared")
+        md.IfFlavor(markdown.GitHubMarkdown, func(md *markdown.Markdown) {
+            md.Alert(markdown.AlertNote, "GitHub only")
+        })
+        return md.GetContent()
+    }
+    compareOutput(t, "TestIfFlavor GitHub", "Shared\n\n> [!NOTE]\n> GitHub only\n\n", build(markdown.GitHubMarkdown))
+    compareOutput(t, "TestIfFlavor standard", "Shared\n\n", build(markdown.StandardMarkdown))
+}
+
+func TestTemplate(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Template("# {{title}}\n\nVersion {{ version }} by {{author}}.\n\n", map[string]string{"title": "Release", "version": "1.2"})
+    compareOutput(t, "TestTemplate", "# Release\n\nVersion 1.2 by {{author}}.\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.SetTemplateEscape(true)
+    md.Template("Name: {{name}}\n\n", map[string]string{"name": "*not bold*"})
+    compareOutput(t, "TestTemplate escaped", "Name: \\*not bold\\*\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Template("Hello {{x}}", map[string]string{"x": "world"})
+    md.Paragraph("next")
+    compareOutput(t, "TestTemplate followed by a block", "Hello world\n\nnext\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.SetStrict(true)
+    md.Template("Hello {{name}}\n\n", nil)
+    if md.Err() == nil || md.Err().Error() != "markdown: template has no value for name" {
+        t.Errorf("Template returned unexpected error in strict mode: %v", md.Err())
+    }
+    compareOutput(t, "TestTemplate strict", "", md.GetContent())
+}
+
+func TestInclude(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "partial.md")
+    partial := "# Partial\n\nBody text.\n\n```sh\n# not a heading\n```\n"
+    if err := os.WriteFile(path, []byte(partial), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("Intro")
+    if err := md.Include(path); err != nil {
+        t.Fatalf("Include returned error: %v", err)
+    }
+    compareOutput(t, "TestInclude", "Intro\n\n"+partial+"\n", md.GetContent())
+    if err := md.Include(path); err == nil {
+        t.Errorf("Include accepted the same file twice")
+    }
+    if err := md.Include(filepath.Join(t.TempDir(), "missing.md")); err == nil {
+        t.Errorf("Include accepted a missing file")
+    }
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    if err := md.IncludeWithOffset(path, 2); err != nil {
+        t.Fatalf("IncludeWithOffset returned error: %v", err)
+    }
+    compareOutput(t, "TestInclude offset", "### Partial\n\nBody text.\n\n```sh\n# not a heading\n```\n\n", md.GetContent())
+}
+
+func TestLen(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    if md.Len() != 0 {
+        t.Errorf("Len of an empty document = %d, expected 0", md.Len())
+    }
+    md.Paragraph("First")
+    first := md.Len()
+    md.Heading(2, "Second", "", "")
+    if md.Len() <= first || md.Len() != len(md.GetContent()) {
+        t.Errorf("Len = %d after writes, expected %d", md.Len(), len(md.GetContent()))
+    }
+}
+
+func TestBytesAndWriteTo(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Heading(1, "Title", "", "")
+    md.Paragraph("Body")
+    if !bytes.Equal(md.Bytes(), []byte(md.GetContent())) {
+        t.Errorf("Bytes = %q, expected %q", md.Bytes(), md.GetContent())
+    }
+    md.Bytes()[0] = 'X' // Must not affect the document
+    var buf bytes.Buffer
+    n, err := md.WriteTo(&buf)
+    if err != nil || n != int64(md.Len()) {
+        t.Errorf("WriteTo = %d, %v, expected %d, nil", n, err, md.Len())
+    }
+    compareOutput(t, "TestBytesAndWriteTo", "# Title\n\nBody\n\n", buf.String())
+}
+
+func TestListOfFiguresAndTables(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.ListOfFigures() // Nothing to list yet
+    md.Figure("Cat", "cat.png", "A cat")
+    md.Figure("Logo", "logo.png", "")
+    md.Figure("Dog", "dog.png", "A dog")
+    md.NewTable("Name", "Age").AddRow("Tom", "3").SetAlign("left", "right").SetCaption("Pets").Render()
+    md.NewTable("Name").AddRow("Rex").SetCaption("Dogs").RenderHTML()
+    md.ListOfFigures()
+    md.ListOfTables()
+    expected := "<figure id=\"figure-1\"><img src=\"cat.png\" alt=\"Cat\"><figcaption>A cat</figcaption></figure>\n\n" +
+        "<figure><img src=\"logo.png\" alt=\"Logo\"></figure>\n\n" +
+        "<figure id=\"figure-2\"><img src=\"dog.png\" alt=\"Dog\"><figcaption>A dog</figcaption></figure>\n\n" +
+        "<a id=\"table-1\"></a>**Pets**\n\n| Name | Age |\n|:---|---:|\n| Tom | 3 |\n\n" +
+        "<table id=\"table-2\">\n<caption>Dogs</caption>\n<thead>\n<tr><th>Name</th></tr>\n</thead>\n<tbody>\n<tr><td>Rex</td></tr>\n</tbody>\n</table>\n\n" +
+        "1. [A cat](#figure-1)\n2. [A dog](#figure-2)\n\n" +
+        "1. [Pets](#table-1)\n2. [Dogs](#table-2)\n\n"
+    compareOutput(t, "TestListOfFiguresAndTables", expected, md.GetContent())
+}
+
+func TestRef(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Heading(2, "Getting Started", "", "")
+    md.Heading(2, "API Reference", "api", "")
+    md.Paragraph("See " + md.Ref("Getting Started") + " and " + md.Ref("API Reference") + ".")
+    compareOutput(t, "TestRef", "## Getting Started\n\n## API Reference {#api}\n\nSee [Getting Started](#getting-started) and [API Reference](#api).\n\n", md.GetContent())
+
+    compareOutput(t, "TestRef missing", "", md.Ref("Missing"))
+    if md.Err() != nil {
+        t.Errorf("Ref recorded an error outside strict mode: %v", md.Err())
+    }
+    md.SetStrict(true)
+    md.Ref("Missing")
+    if md.Err() == nil || md.Err().Error() != "markdown: no heading \"Missing\" to reference" {
+        t.Errorf("Ref returned unexpected error in strict mode: %v", md.Err())
+    }
+
+    md = markdown.New(markdown.GitHubMarkdown, false).WithHeadingNumbers(true)
+    md.Heading(1, "Intro", "", "")
+    compareOutput(t, "TestRef numbered", "[Intro](#1-intro)", md.Ref("Intro"))
+
+    md = markdown.New(markdown.GitHubMarkdown, false).WithAutoEscape(true)
+    md.Heading(1, "C++ tips", "", "")
+    compareOutput(t, "TestRef escaped", "[C++ tips](#c-tips)", md.Ref("C++ tips"))
+}
+
+func TestFileTree(t *testing.T) {
+    root := filepath.Join(t.TempDir(), "project")
+    for _, dir := range []string{"cmd/tool", "docs", ".git"} {
+        if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+            t.Fatal(err)
+        }
+    }
+    for _, file := range []string{"go.mod", "cmd/tool/main.go", "docs/index.md", ".gitignore"} {
+        if err := os.WriteFile(filepath.Join(root, file), nil, 0o644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.SetSkipHiddenFiles(true)
+    if err := md.FileTree(root, 0); err != nil {
+        t.Fatalf("FileTree returned error: %v", err)
+    }
+    expected := "```\nproject/\n├── cmd/\n│   └── tool/\n│       └── main.go\n├── docs/\n│   └── index.md\n└── go.mod\n```\n\n"
+    compareOutput(t, "TestFileTree", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    if err := md.FileTree(root, 1); err != nil {
+        t.Fatalf("FileTree returned error: %v", err)
+    }
+    expected = "```\nproject/\n├── .git/\n├── .gitignore\n├── cmd/\n├── docs/\n└── go.mod\n```\n\n"
+    compareOutput(t, "TestFileTree depth", expected, md.GetContent())
+
+    if err := md.FileTree(filepath.Join(root, "missing"), 0); err == nil {
+        t.Errorf("FileTree accepted a missing directory")
+    }
+}
+
+func TestTree(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Tree(markdown.TreeNode{Label: "app", Children: []markdown.TreeNode{
+        {Label: "api", Children: []markdown.TreeNode{{Label: "auth"}, {Label: "storage"}}},
+        {Label: "web", Children: []markdown.TreeNode{{Label: "ui"}}},
+    }})
+    md.Tree(markdown.TreeNode{})
+    expected := "```\napp\n├── api\n│   ├── auth\n│   └── storage\n└── web\n    └── ui\n```\n\n"
+    compareOutput(t, "TestTree", expected, md.GetContent())
+}
+
+func TestDefinitionListPandoc(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.DefinitionListPandoc([]markdown.OrderedDefinition{
+        markdown.NewOrderedDefinition("Markdown", "A lightweight markup language\nwith plain-text formatting.", "A file format."),
+        markdown.NewOrderedDefinition("Empty"),
+        markdown.NewOrderedDefinition("Pandoc", "A document converter."),
+    })
+    expected := "Markdown\n\n:   A lightweight markup language\n    with plain-text formatting.\n:   A file format.\n\n" +
+        "Pandoc\n\n:   A document converter.\n\n"
+    compareOutput(t, "TestDefinitionListPandoc", expected, md.GetContent())
+}
+
+func TestLineBlock(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.LineBlock([]string{"Jane Doe", "  123 Main Street", "Springfield"})
+    md.LineBlock(nil)
+    compareOutput(t, "TestLineBlock", "| Jane Doe\n| \\ \\ 123 Main Street\n| Springfield\n\n", md.GetContent())
+}
+
+func TestOrderedListStyle(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.OrderedListStyle([]string{"One", "Two", "Three", "Four"}, "lower-roman")
+    md.OrderedListStyle([]string{"First", "Second"}, "upper-alpha")
+    md.OrderedListStyle([]string{"Plain"}, "decimal")
+    expected := "i. One\\\nii. Two\\\niii. Three\\\niv. Four\n\n" +
+        "A. First\\\nB. Second\n\n" +
+        "1. Plain\n\n"
+    compareOutput(t, "TestOrderedListStyle", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.SetHTMLOutput(true)
+    md.OrderedListStyle([]string{"a < b", "c"}, "lower-alpha")
+    compareOutput(t, "TestOrderedListStyle HTML", "<ol type=\"a\">\n<li>a &lt; b</li>\n<li>c</li>\n</ol>\n\n", md.GetContent())
+
+    md.SetStrict(true)
+    md.OrderedListStyle([]string{"x"}, "greek")
+    if md.Err() == nil {
+        t.Errorf("OrderedListStyle accepted an unknown style in strict mode")
+    }
+}
+
+func TestListItemWithBody(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.ListItemWithBody("1.", "Install the tool:", func(md *markdown.Markdown) {
+        md.CodeBlock("sh", "go install example.com/tool@latest")
+        md.Paragraph("Then check the version.")
+    })
+    md.ListItemWithBody("2.", "Run it.", nil)
+    expected := "1. Install the tool:\n\n" +
+        "   ```sh\n   go install example.com/tool@latest\n   ```\n\n   Then check the version.\n\n" +
+        "2. Run it.\n\n"
+    compareOutput(t, "TestListItemWithBody", expected, md.GetContent())
+}
+
+func TestBibliography(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.Paragraph("Literate programming " + md.Cite("knuth1984") + " and structured programming " + md.Cite("dijkstra1968") + ".")
+    md.Bibliography(map[string]string{
+        "knuth1984": "Knuth, D. E. (1984). Literate Programming.",
+        "wirth1971": "Wirth, N. (1971). Program Development by Stepwise Refinement.",
+    })
+    expected := "Literate programming [@knuth1984] and structured programming [@dijkstra1968].\n\n" +
+        "## References\n\n" +
+        "- **knuth1984**: Knuth, D. E. (1984). Literate Programming.\n" +
+        "- **wirth1971**: Wirth, N. (1971). Program Development by Stepwise Refinement.\n\n"
+    compareOutput(t, "TestBibliography", expected, md.GetContent())
+    compareOutput(t, "TestBibliography warnings",
+        "markdown: citation \"dijkstra1968\" has no bibliography entry\nmarkdown: reference \"wirth1971\" is never cited",
+        strings.Join(md.Warnings(), "\n"))
+}
+
+func TestStats(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Heading(1, "Guide", "", "")
+    md.Paragraph("Read the [manual](https://example.com/manual) first.")
+    md.Heading(2, "Setup", "", "")
+    md.Paragraph("Install it.\nThen run it.")
+    md.CodeBlock("sh", "# not a heading\n[not](a-link)")
+    md.Image("Logo", "logo.png")
+    md.List([]string{"one item"}, false)
+    md.Table([]string{"A", "B"}, [][]string{{"1", "2"}}, []string{"left", "left"})
+    md.Heading(2, "Usage", "", "")
+    stats := md.Stats()
+    expected := markdown.DocStats{
+        Headings:   [6]int{1, 2},
+        Paragraphs: 3,
+        CodeBlocks: 1,
+        Links:      1,
+        Images:     1,
+        Tables:     1,
+        Words:      19,
+    }
+    if stats != expected {
+        t.Errorf("Stats = %+v, expected %+v", stats, expected)
+    }
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.SetExtractInlineLinks(true)
+    md.Paragraph("See [one](https://a.example) and [two](https://b.example).")
+    if stats := md.Stats(); stats.Links != 2 || stats.Words != 4 {
+        t.Errorf("Stats with link extraction = %+v, expected 2 links and 4 words", stats)
+    }
+}
+
+func TestDiff(t *testing.T) {
+    build := func(version string) *markdown.Markdown {
+        md := markdown.New(markdown.GitHubMarkdown, false)
+        md.Heading(1, "Release notes", "", "")
+        md.Paragraph("Version " + version)
+        md.List([]string{"Faster builds", "Fewer bugs", "New logo"}, false)
+        return md
+    }
+    a, b := build("1.0"), build("1.1")
+    compareOutput(t, "TestDiff equal", "", markdown.Diff(a, build("1.0")))
+    expected := "--- a\n+++ b\n" +
+        "@@ -1,6 +1,6 @@\n" +
+        " # Release notes\n \n-Version 1.0\n+Version 1.1\n \n - Faster builds\n - Fewer bugs\n"
+    compareOutput(t, "TestDiff", expected, markdown.Diff(a, b))
+
+    b.Paragraph("Thanks!")
+    expected = "--- a\n+++ b\n" +
+        "@@ -1,8 +1,10 @@\n" +
+        " # Release notes\n \n-Version 1.0\n+Version 1.1\n \n - Faster builds\n - Fewer bugs\n - New logo\n \n+Thanks!\n+\n"
+    compareOutput(t, "TestDiff merged hunks", expected, markdown.Diff(a, b))
+
+    a, b = markdown.New(markdown.GitHubMarkdown, false), markdown.New(markdown.GitHubMarkdown, false)
+    for i := 1; i <= 10; i++ {
+        line := fmt.Sprintf("Line %d", i)
+        a.Paragraph(line)
+        if i == 1 || i == 10 {
+            line += "!"
+        }
+        b.Paragraph(line)
+    }
+    expected = "--- a\n+++ b\n" +
+        "@@ -1,4 +1,4 @@\n-Line 1\n+Line 1!\n \n Line 2\n \n" +
+        "@@ -16,5 +16,5 @@\n \n Line 9\n \n-Line 10\n+Line 10!\n \n"
+    compareOutput(t, "TestDiff two hunks", expected, markdown.Diff(a, b))
+}
+
+func TestFootnoteRef(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    compareOutput(t, "TestFootnoteRef", "[^1]", md.FootnoteRef("1"))
+    md.SetSuperscriptFootnotes(true)
+    md.Paragraph("See the note" + md.FootnoteRef("note") + ".")
+    compareOutput(t, "TestFootnoteRef superscript", "See the note<sup>[^note]</sup>.\n\n", md.GetContent())
+    compareOutput(t, "TestFootnoteRef empty", "", md.FootnoteRef(""))
+}
+
+func TestEscapeDocument(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    input := "Use *stars* and `a*b_c` here.\n\n```go\nx := a * b // [not] escaped\n```\n\n# Title"
+    expected := "Use \\*stars\\* and `a*b_c` here\\.\n\n```go\nx := a * b // [not] escaped\n```\n\n\\# Title"
+    compareOutput(t, "TestEscapeDocument", expected, md.EscapeDocument(input))
+
+    input = `See <https://example.com/a_b> for *C:\dir*`
+    expected = `See <https://example.com/a_b> for \*C:\\dir\*`
+    compareOutput(t, "TestEscapeDocument autolink", expected, md.EscapeDocument(input))
+    md.WithAutoEscape(true).Paragraph(input)
+    compareOutput(t, "TestEscapeDocument matches auto-escape", expected+"\n\n", md.GetContent())
+}
+
+func TestSection(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Section("intro\"x", func(md *markdown.Markdown) {
+        md.Heading(2, "Intro", "", "")
+        md.Section("details", func(md *markdown.Markdown) {
+            md.Paragraph("Nested content.")
+        })
+    })
+    expected := "<section id=\"intro&#34;x\">\n\n## Intro\n\n<section id=\"details\">\n\nNested content.\n\n</section>\n\n</section>\n\n"
+    compareOutput(t, "TestSection", expected, md.GetContent())
+    compareOutput(t, "TestSection heading tracked", "[Intro](#intro)", md.Ref("Intro"))
+}
+
+func TestColumns(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Columns(
+        func(md *markdown.Markdown) { md.Paragraph("Left side.") },
+        func(md *markdown.Markdown) { md.Paragraph("Right side.") },
+    )
+    expected := "<div style=\"display: flex; gap: 1em;\">\n" +
+        "<div style=\"flex: 1;\">\n\nLeft side.\n\n</div>\n" +
+        "<div style=\"flex: 1;\">\n\nRight side.\n\n</div>\n" +
+        "</div>\n\n"
+    compareOutput(t, "TestColumns", expected, md.GetContent())
+}
+
+func TestTabs(t *testing.T) {
+    tabs := []markdown.Tab{
+        {Title: "Go", Body: func(md *markdown.Markdown) { md.CodeBlock("go", "fmt.Println(\"hi\")") }},
+        {Title: "Shell", Body: func(md *markdown.Markdown) { md.Paragraph("Run `echo hi`.") }},
+        {Title: "Empty"},
+    }
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Tabs(tabs)
+    expected := "=== \"Go\"\n\n    ```go\n    fmt.Println(\"hi\")\n    ```\n\n" +
+        "=== \"Shell\"\n\n    Run `echo hi`.\n\n"
+    compareOutput(t, "TestTabs", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.SetTabStyle(markdown.TabsDetails)
+    md.Tabs(tabs[1:])
+    compareOutput(t, "TestTabs details", "<details>\n<summary>Shell</summary>\n\nRun `echo hi`.\n\n</details>\n\n", md.GetContent())
+}
+
+func TestGallery(t *testing.T) {
+    var images []markdown.ImageSpec
+    for i := 1; i <= 6; i++ {
+        images = append(images, markdown.ImageSpec{Alt: fmt.Sprintf("Shot %d", i), URL: fmt.Sprintf("s%d.png", i)})
+    }
+    images[0].Link = "https://example.com"
+    images = append(images, markdown.ImageSpec{Alt: "No URL"})
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Gallery(images, 3)
+    expected := "|  |  |  |\n|:---:|:---:|:---:|\n" +
+        "| [![Shot 1](s1.png)](https://example.com) | ![Shot 2](s2.png) | ![Shot 3](s3.png) |\n" +
+        "| ![Shot 4](s4.png) | ![Shot 5](s5.png) | ![Shot 6](s6.png) |\n\n"
+    compareOutput(t, "TestGallery", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Gallery([]markdown.ImageSpec{{Alt: "a|b", URL: "x.png"}}, 1)
+    compareOutput(t, "TestGallery pipe", "|  |\n|:---:|\n| ![a\\|b](x.png) |\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.SetHTMLOutput(true)
+    md.Gallery(images[4:], 0)
+    expected = "<div style=\"display: grid; grid-template-columns: repeat(1, 1fr); gap: 1em;\">\n" +
+        "<img src=\"s5.png\" alt=\"Shot 5\">\n<img src=\"s6.png\" alt=\"Shot 6\">\n</div>\n\n"
+    compareOutput(t, "TestGallery HTML", expected, md.GetContent())
+}
+
+func TestSetBaseURL(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.SetBaseURL("https://example.com/docs/guide/")
+    md.Paragraph(md.Link("Install", "../install.md") + " " + md.Link("Home", "https://go.dev/") + " " + md.Link("Usage", "#usage"))
+    md.Image("Logo", "img/logo.png")
+    expected := "[Install](https://example.com/docs/install.md) [Home](https://go.dev/) [Usage](#usage)\n\n" +
+        "![Logo](https://example.com/docs/guide/img/logo.png)\n\n"
+    compareOutput(t, "TestSetBaseURL", expected, md.GetContent())
+
+    md.SetStrict(true)
+    md.SetBaseURL("relative/path")
+    if md.Err() == nil {
+        t.Errorf("SetBaseURL accepted a relative base in strict mode")
+    }
+}
+
+func TestToReferenceStyle(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("Read the [guide](https://example.com/guide) and the [FAQ](https://example.com/faq).")
+    md.Paragraph("Again, the [user guide](https://example.com/guide) helps. Keep `[code](x)` as is.")
+    expected := "Read the [guide][1] and the [FAQ][2].\n\n" +
+        "Again, the [user guide][1] helps. Keep `[code](x)` as is.\n\n" +
+        "[1]: https://example.com/guide\n[2]: https://example.com/faq\n"
+    compareOutput(t, "TestToReferenceStyle", expected, md.ToReferenceStyle())
+    if strings.Contains(md.GetContent(), "[1]") {
+        t.Errorf("ToReferenceStyle changed the document content")
+    }
+}
+
+func TestAutoFootnote(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("First" + md.AutoFootnote("One.") + ", second" + md.AutoFootnote("Two.") + ".")
+    md.Paragraph("Third" + md.AutoFootnote("Three.") + ".")
+    md.RenderFootnotes()
+    md.RenderFootnotes() // Nothing left to render
+    expected := "First[^1], second[^2].\n\nThird[^3].\n\n[^1]: One.\n[^2]: Two.\n[^3]: Three.\n\n"
+    compareOutput(t, "TestAutoFootnote", expected, md.GetContent())
+
+    var buf bytes.Buffer
+    sm := markdown.NewWriter(&buf, markdown.GitHubMarkdown, false)
+    sm.Paragraph("Streamed" + sm.AutoFootnote("Deferred."))
+    if err := sm.Close(); err != nil {
+        t.Fatalf("Close returned error: %v", err)
+    }
+    compareOutput(t, "TestAutoFootnote stream", "Streamed[^1]\n\n[^1]: Deferred.\n\n", buf.String())
+}
+
+func TestNestedSection(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.NestedSection("Guide", func(md *markdown.Markdown) {
+        md.Paragraph("Overview.")
+        md.NestedSection("Setup", func(md *markdown.Markdown) {
+            md.NestedSection("Linux", func(md *markdown.Markdown) {
+                md.Paragraph("Use the package manager.")
+            })
+        })
+        md.NestedSection("Usage", nil)
+    })
+    md.NestedSection("Appendix", nil)
+    expected := "# Guide\n\nOverview.\n\n## Setup\n\n### Linux\n\nUse the package manager.\n\n## Usage\n\n# Appendix\n\n"
+    compareOutput(t, "TestNestedSection", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false).WithThreadSafe(true)
+    var nest func(md *markdown.Markdown, level int)
+    nest = func(md *markdown.Markdown, level int) {
+        if level <= 7 {
+            md.NestedSection(fmt.Sprintf("L%d", level), func(md *markdown.Markdown) { nest(md, level+1) })
+        }
+    }
+    nest(md, 1)
+    md.NestedSection("Top", nil)
+    expected = "# L1\n\n## L2\n\n### L3\n\n#### L4\n\n##### L5\n\n###### L6\n\n###### L7\n\n# Top\n\n"
+    compareOutput(t, "TestNestedSection deep", expected, md.GetContent())
+}
+
+func TestDefinitionListParagraphs(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.DefinitionListPandoc([]markdown.OrderedDefinition{
+        markdown.NewOrderedDefinitionParagraphs("Cache", [][]string{
+            {"A store for computed results.", "Entries expire after\na configurable time."},
+            {"A hidden stash."},
+        }),
+    })
+    expected := "Cache\n\n:   A store for computed results.\n\n    Entries expire after\n    a configurable time.\n:   A hidden stash.\n\n"
+    compareOutput(t, "TestDefinitionListParagraphs", expected, md.GetContent())
+}
+
+func TestBlockSpacing(t *testing.T) {
+    build := func(lines int) string {
+        md := markdown.New(markdown.GitHubMarkdown, false).WithBlockSpacing(lines)
+        md.Heading(1, "Title", "", "")
+        md.Paragraph("Body")
+        md.Alert(markdown.AlertNote, "Note")
+        return md.GetContent()
+    }
+    compareOutput(t, "TestBlockSpacing default", "# Title\n\nBody\n\n> [!NOTE]\n> Note\n\n", build(1))
+    compareOutput(t, "TestBlockSpacing 0", "# Title\nBody\n> [!NOTE]\n> Note\n", build(0))
+    compareOutput(t, "TestBlockSpacing 2", "# Title\n\n\nBody\n\n\n> [!NOTE]\n> Note\n\n\n", build(2))
+
+    md := markdown.New(markdown.GitHubMarkdown, false).WithBlockSpacing(0)
+    md.Section("s", func(md *markdown.Markdown) {
+        md.Heading(2, "Title", "", "")
+        md.Paragraph("body")
+    })
+    md.Columns(func(md *markdown.Markdown) { md.Paragraph("left") }, func(md *markdown.Markdown) { md.Paragraph("right") })
+    md.Paragraph("after")
+    expected := "<section id=\"s\">\n\n## Title\nbody\n\n</section>\n\n" +
+        "<div style=\"display: flex; gap: 1em;\">\n<div style=\"flex: 1;\">\n\nleft\n\n</div>\n" +
+        "<div style=\"flex: 1;\">\n\nright\n\n</div>\n</div>\n\nafter\n"
+    compareOutput(t, "TestBlockSpacing HTML wrappers", expected, md.GetContent())
+}
+
+func TestFinalize(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false).WithBlockSpacing(2)
+    md.Paragraph("Text with trailing spaces   ")
+    md.CodeBlock("", "keep   \n\n\nthis")
+    compareOutput(t, "TestFinalize", "Text with trailing spaces\n\n```\nkeep   \n\n\nthis\n```\n", md.Finalize())
+    md.Paragraph("More")
+    if !strings.HasSuffix(md.GetContent(), "More\n\n\n") {
+        t.Errorf("Finalize changed the document: %q", md.GetContent())
+    }
+}
+
+func TestPrepend(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Heading(2, "Body", "", "")
+    md.Paragraph("Text")
+    md.Prepend("**Summary:** one section\n")
+    md.Prepend("")
+    compareOutput(t, "TestPrepend", "**Summary:** one section\n\n## Body\n\nText\n\n", md.GetContent())
+
+    var buf bytes.Buffer
+    sm := markdown.NewWriter(&buf, markdown.GitHubMarkdown, false)
+    sm.SetStrict(true)
+    sm.Paragraph("Streamed")
+    sm.Prepend("Too late")
+    if sm.Err() == nil {
+        t.Errorf("Prepend did not report an error for a streamed document")
+    }
+}
+
+func TestRenderTOC(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Heading(1, "Guide", "", "")
+    md.Paragraph("Introduction.")
+    md.TOCPlaceholder()
+    md.Heading(2, "Install", "", "")
+    md.Heading(3, "Linux", "", "")
+    md.Heading(2, "Usage", "", "")
+    md.RenderTOC()
+    expected := "# Guide\n\nIntroduction.\n\n" +
+        "- [Guide](#guide)\n  - [Install](#install)\n    - [Linux](#linux)\n  - [Usage](#usage)\n\n" +
+        "## Install\n\n### Linux\n\n## Usage\n\n"
+    compareOutput(t, "TestRenderTOC", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.TOCPlaceholder()
+    md.Paragraph("No headings.")
+    md.RenderTOC()
+    compareOutput(t, "TestRenderTOC empty", "No headings.\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.CodeBlock("markdown", "<!-- toc -->")
+    md.Paragraph("Inline `<!-- toc -->` marker.")
+    md.TOCPlaceholder()
+    md.Heading(2, "Usage", "", "")
+    md.RenderTOC()
+    expected = "```markdown\n<!-- toc -->\n```\n\nInline `<!-- toc -->` marker.\n\n- [Usage](#usage)\n\n## Usage\n\n"
+    compareOutput(t, "TestRenderTOC marker in code", expected, md.GetContent())
+    md.SetStrict(true)
+    md.RenderTOC()
+    if md.Err() == nil {
+        t.Errorf("RenderTOC without placeholder did not record an error in strict mode")
+    }
+}
+
+func TestCollapsibleTOC(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Heading(1, "Guide", "", "")
+    md.Heading(2, "Usage", "", "")
+    md.TableOfContents()
+    md.SetCollapsibleTOC(true)
+    md.TableOfContents()
+    expected := "# Guide\n\n## Usage\n\n" +
+        "- [Guide](#guide)\n  - [Usage](#usage)\n\n" +
+        "<details>\n<summary>Table of Contents</summary>\n\n- [Guide](#guide)\n  - [Usage](#usage)\n\n</details>\n\n"
+    compareOutput(t, "TestCollapsibleTOC", expected, md.GetContent())
+}
+
+func TestTOCOptions(t *testing.T) {
+    build := func(opts markdown.TOCOptions) string {
+        md := markdown.New(markdown.GitHubMarkdown, false)
+        md.SetTOCOptions(opts)
+        md.Heading(1, "Guide", "", "")
+        md.Heading(2, "Install", "", "")
+        md.Heading(3, "Linux", "", "")
+        md.Heading(2, "Usage", "", "")
+        md.Heading(3, "CLI", "", "")
+        md.Heading(3, "API", "", "")
+        headings := md.GetContent()
+        md.TableOfContents()
+        return strings.TrimPrefix(md.GetContent(), headings)
+    }
+    compareOutput(t, "TestTOCOptions max level", "- [Guide](#guide)\n  - [Install](#install)\n  - [Usage](#usage)\n\n",
+        build(markdown.TOCOptions{MaxLevel: 2}))
+    compareOutput(t, "TestTOCOptions numbered",
+        "- 1 [Install](#install)\n  - 1.1 [Linux](#linux)\n- 2 [Usage](#usage)\n  - 2.1 [CLI](#cli)\n  - 2.2 [API](#api)\n\n",
+        build(markdown.TOCOptions{MinLevel: 2, Numbered: true}))
+
+    md := markdown.New(markdown.GitHubMarkdown, false).WithHeadingNumbers(true)
+    md.SetTOCOptions(markdown.TOCOptions{Numbered: true})
+    md.Heading(1, "A", "", "")
+    md.Heading(2, "B", "", "")
+    headings := md.GetContent()
+    md.TableOfContents()
+    compareOutput(t, "TestTOCOptions heading numbers", "- [1 A](#1-a)\n  - [1.1 B](#11-b)\n\n",
+        strings.TrimPrefix(md.GetContent(), headings))
+}
+
+func TestKeyValues(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.KeyValues([]markdown.KV{
+        {Key: "Method", Value: "GET"},
+        {Key: "Path", Value: "/users/*"},
+        {Key: "Returns", Value: "`[]User`", Raw: true},
+        {Value: "ignored"},
+    })
+    compareOutput(t, "TestKeyValues", "**Method:** GET\\\n**Path:** /users/\\*\\\n**Returns:** `[]User`\n\n", md.GetContent())
+}
+
+func TestListItemWithTable(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.ListItemWithBody("-", "Overview", nil)
+    md.ListItemWithBody("-", "Limits:", func(md *markdown.Markdown) {
+        md.HTMLTable(
+            []markdown.TableCell{{Text: "Plan"}, {Text: "Requests"}},
+            [][]markdown.TableCell{{{Text: "Free"}, {Text: "100"}}},
+        )
+    })
+    md.ListItemWithBody("-", "Pricing", nil)
+    expected := "- Overview\n\n" +
+        "- Limits:\n\n" +
+        "  <table>\n  <thead>\n  <tr><th>Plan</th><th>Requests</th></tr>\n  </thead>\n" +
+        "  <tbody>\n  <tr><td>Free</td><td>100</td></tr>\n  </tbody>\n  </table>\n\n" +
+        "- Pricing\n\n"
+    compareOutput(t, "TestListItemWithTable", expected, md.GetContent())
+
+    // Wider markers indent the table further, and pipe tables are indented the
+    // same way. Every table line must sit inside the item, after a blank line,
+    // for renderers to parse it as a table belonging to the item.
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.ListItemWithBody("10.", "Limits:", func(md *markdown.Markdown) {
+        md.Table([]string{"Plan", "Requests"}, [][]string{{"Free", "100"}}, []string{"left", "right"})
+        md.HTMLTable([]markdown.TableCell{{Text: "Plan"}}, [][]markdown.TableCell{{{Text: "Free"}}})
+    })
+    expected = "10. Limits:\n\n" +
+        "    | Plan | Requests |\n    |:---|---:|\n    | Free | 100 |\n\n" +
+        "    <table>\n    <thead>\n    <tr><th>Plan</th></tr>\n    </thead>\n" +
+        "    <tbody>\n    <tr><td>Free</td></tr>\n    </tbody>\n    </table>\n\n"
+    compareOutput(t, "TestListItemWithTable wide marker", expected, md.GetContent())
+    for _, line := range strings.Split(strings.TrimSpace(md.GetContent()), "\n")[2:] {
+        if line != "" && !strings.HasPrefix(line, "    ") {
+            t.Errorf("TestListItemWithTable: table line %q is not indented into the list item", line)
+        }
+    }
+}
+
+func TestGlossary(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("Call the " + md.GlossaryLink("API") + " with a " + md.GlossaryLink("token") + " or " + md.GlossaryLink("OAuth") + ".")
+    md.Glossary(map[string]string{
+        "Token": "A credential that grants access.",
+        "API":   "Application programming interface.",
+    })
+    expected := "Call the [API](#glossary-api) with a [token](#glossary-token) or [OAuth](#glossary-oauth).\n\n" +
+        "## Glossary\n\n" +
+        "<a id=\"glossary-api\"></a>API\n: Application programming interface.\n\n" +
+        "<a id=\"glossary-token\"></a>Token\n: A credential that grants access.\n\n"
+    compareOutput(t, "TestGlossary", expected, md.GetContent())
+    compareOutput(t, "TestGlossary link after", "[Token](#glossary-token)", md.GlossaryLink("Token"))
+    md.GlossaryLink("SDK")
+    compareOutput(t, "TestGlossary warnings",
+        "markdown: glossary term \"OAuth\" is not defined\nmarkdown: glossary term \"SDK\" is not defined",
+        strings.Join(md.Warnings(), "\n"))
+}
+
+func TestChangelog(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Changelog([]markdown.Release{
+        {Version: "1.1.0", Date: "2024-05-01", Added: []string{"Tabs", "Gallery"}, Fixed: []string{"Footnote order"}},
+        {Version: "1.0.0", Date: "2024-01-15", Changed: []string{"New API"}},
+        {Added: []string{"Skipped without version"}},
+    })
+    expected := "# Changelog\n\n" +
+        "## [1.1.0] - 2024-05-01\n\n### Added\n\n- Tabs\n- Gallery\n\n### Fixed\n\n- Footnote order\n\n" +
+        "## [1.0.0] - 2024-01-15\n\n### Changed\n\n- New API\n\n"
+    compareOutput(t, "TestChangelog", expected, md.GetContent())
+}
+
+func TestAutoLinkURLs(t *testing.T) {
+    text := "Docs at https://example.com/docs. See [the site](https://example.com), <https://go.dev>, " +
+        "`https://in.code` and (https://example.com/a_(b))."
+    md := markdown.New(markdown.StandardMarkdown, false).WithAutoLinkURLs(true)
+    md.Paragraph(text)
+    expected := "Docs at <https://example.com/docs>. See [the site](https://example.com), <https://go.dev>, " +
+        "`https://in.code` and (<https://example.com/a_(b)>).\n\n"
+    compareOutput(t, "TestAutoLinkURLs", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false).WithAutoLinkURLs(true)
+    md.Paragraph("Docs at https://example.com/docs.")
+    compareOutput(t, "TestAutoLinkURLs GitHub", "Docs at https://example.com/docs.\n\n", md.GetContent())
+}
+
+func TestTypographyHelpers(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    compareOutput(t, "TestTypographyHelpers NBSP", "&nbsp;", md.NBSP())
+    compareOutput(t, "TestTypographyHelpers SoftHyphen", "&shy;", md.SoftHyphen())
+    compareOutput(t, "TestTypographyHelpers NoBreak", "Visual&nbsp;Studio&nbsp;Code", md.NoBreak(" Visual  Studio Code "))
+}
+
+func TestReader(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Heading(1, "Title", "", "")
+    md.Paragraph("Body text.")
+    r := md.Reader()
+    md.Paragraph("Added after the snapshot.")
+    data, err := io.ReadAll(r)
+    if err != nil {
+        t.Fatalf("TestReader: unexpected error: %v", err)
+    }
+    compareOutput(t, "TestReader", "# Title\n\nBody text.\n\n", string(data))
+}
+
+func TestSnapshotRestore(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Heading(1, "Title", "", "")
+    mark := md.Snapshot()
+    md.Heading(2, "Draft", "", "")
+    md.Paragraph("Speculative text" + md.AutoFootnote("A note."))
+    md.RestoreTo(mark)
+    md.Heading(2, "Draft", "", "")
+    md.Paragraph("Kept text" + md.AutoFootnote("Kept note."))
+    md.RenderFootnotes()
+    expected := "# Title\n\n## Draft\n\nKept text[^1]\n\n[^1]: Kept note.\n\n"
+    compareOutput(t, "TestSnapshotRestore", expected, md.GetContent())
+
+    mark = md.Snapshot()
+    md.TableOfContents()
+    content := md.ContentSince(mark)
+    if !strings.Contains(content, "(#draft)") || strings.Contains(content, "draft-1") {
+        t.Errorf("TestSnapshotRestore: restored headings not rolled back:\n%s", content)
+    }
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("Text" + md.AutoFootnote("Kept note."))
+    first := md.Snapshot()
+    md.AutoFootnote("Discarded note.")
+    second := md.Snapshot()
+    if first == second {
+        t.Errorf("TestSnapshotRestore: snapshots at the same length share the mark %d", first)
+    }
+    md.RestoreTo(first)
+    md.RenderFootnotes()
+    compareOutput(t, "TestSnapshotRestore same length", "Text[^1]\n\n[^1]: Kept note.\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.SetStrict(true)
+    md.RestoreTo(100)
+    if md.Err() == nil {
+        t.Error("TestSnapshotRestore: expected an error for a mark beyond the content")
+    }
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    mark = md.Snapshot()
+    md.Paragraph(md.GlossaryLink("API"))
+    md.Glossary(map[string]string{"SDK": "Software development kit"})
+    md.EmojiInline("not_an_emoji")
+    md.RestoreTo(mark)
+    if warnings := md.Warnings(); len(warnings) != 0 {
+        t.Errorf("TestSnapshotRestore: warnings not rolled back: %v", warnings)
+    }
+    if n := md.UnknownEmojiCount(); n != 0 {
+        t.Errorf("TestSnapshotRestore: unknown emoji count not rolled back: %d", n)
+    }
+    md.Paragraph(md.GlossaryLink("SDK"))
+    md.Glossary(map[string]string{"API": "Application programming interface"})
+    if warnings := md.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "SDK") {
+        t.Errorf("TestSnapshotRestore: glossary state not rolled back: %v", warnings)
+    }
+}
+
+func TestRepeat(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Repeat(3, func(md *markdown.Markdown, i int) {
+        md.Heading(2, fmt.Sprintf("Step %d", i+1), "", "")
+    }).Repeat(0, func(md *markdown.Markdown, i int) {
+        md.Paragraph("never")
+    })
+    expected := "## Step 1\n\n## Step 2\n\n## Step 3\n\n"
+    compareOutput(t, "TestRepeat", expected, md.GetContent())
+}
+
+func TestAutoEscape(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false).WithAutoEscape(true).WithAutoLinkURLs(true)
+    md.Heading(2, "*foo*", "", "")
+    md.Paragraph("User said *foo* and `*code*` at https://example.com")
+    md.List([]string{"_bar_"}, false)
+    md.Table([]string{"Name"}, [][]string{{"**x**"}}, []string{"left"})
+    md.CodeBlock("", "*kept*")
+    expected := "## \\*foo\\*\n\n" +
+        "User said \\*foo\\* and `*code*` at <https://example.com>\n\n" +
+        "- \\_bar\\_\n\n" +
+        "| Name |\n|:---|\n| \\*\\*x\\*\\* |\n\n" +
+        "```\n*kept*\n```\n\n"
+    compareOutput(t, "TestAutoEscape", expected, md.GetContent())
+
+    md = markdown.New(markdown.StandardMarkdown, false).WithAutoEscape(true)
+    md.NewTable("Item", "Cost").AddRow("*a*", "1").SetFooter("Total", "1").Render()
+    md.TaskTree([]markdown.Task{{Text: "_do_", Subtasks: []markdown.Task{{Text: "#1"}}}})
+    md.Paragraph(`C:\path`)
+    expected = "| Item | Cost |\n|---|---|\n| \\*a\\* | 1 |\n| **Total** | **1** |\n\n" +
+        "- [ ] \\_do\\_\n  - [ ] \\#1\n\n" +
+        "C:\\\\path\n\n"
+    compareOutput(t, "TestAutoEscape builder and tasks", expected, md.GetContent())
+
+    md = markdown.New(markdown.StandardMarkdown, false)
+    md.Paragraph("*foo*")
+    compareOutput(t, "TestAutoEscape off", "*foo*\n\n", md.GetContent())
+}
+
+func TestTextDiagram(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.TextDiagram([]string{
+        "+--------+     +--------+",
+        "| Client | --> | Server |",
+        "+--------+     +--------+",
+    })
+    md.TextDiagram([]string{"```", "  fenced  "})
+    md.TextDiagram(nil)
+    expected := "```\n" +
+        "+--------+     +--------+\n" +
+        "| Client | --> | Server |\n" +
+        "+--------+     +--------+\n" +
+        "```\n\n" +
+        "````\n```\n  fenced  \n````\n\n"
+    compareOutput(t, "TestTextDiagram", expected, md.GetContent())
+}
+
+func TestAdmonition(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.Admonition("tip", "Pro tip", "Use the cache.")
+    md.Admonition("warning", "", "Back up first.")
+    md.Admonition("danger", "", ":::note\nNested\n:::")
+    md.Admonition("custom", "", "Skipped.")
+    expected := ":::tip Pro tip\nUse the cache.\n:::\n\n" +
+        ":::warning Warning\nBack up first.\n:::\n\n" +
+        "::::danger Danger\n:::note\nNested\n:::\n::::\n\n"
+    compareOutput(t, "TestAdmonition", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Admonition("info", "Heads up", "Read this.")
+    md.Admonition("note", "", "Plain.")
+    expected = "> [!IMPORTANT]\n> **Heads up**\n>\n> Read this.\n\n> [!NOTE]\n> Plain.\n\n"
+    compareOutput(t, "TestAdmonition GitHub", expected, md.GetContent())
+}
+
+func TestRuleStyle(t *testing.T) {
+    for _, style := range []string{"---", "***", "___"} {
+        md := markdown.New(markdown.GitHubMarkdown, false)
+        md.SetRuleStyle(style)
+        md.Paragraph("Text")
+        md.HorizontalRule()
+        compareOutput(t, "TestRuleStyle "+style, "Text\n\n"+style+"\n\n", md.GetContent())
+    }
+
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Footnote("1", "Note")
+    md.HorizontalRule()
+    compareOutput(t, "TestRuleStyle blank line", "[1]: Note [Return to text](#fn-1-back)\n\n---\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.SetStrict(true)
+    md.SetRuleStyle("===")
+    if md.Err() == nil {
+        t.Error("TestRuleStyle: expected an error for an unknown rule style")
+    }
+}
+
+func TestHeadingWithAttrs(t *testing.T) {
+    md := markdown.New(markdown.StandardMarkdown, false)
+    md.HeadingWithAttrs(2, "Intro", "intro", []string{"lead", "wide"}, map[string]string{"data-level": "1", "class": "ignored"})
+    md.HeadingWithAttrs(3, "Plain", "", nil, nil)
+    md.HeadingWithAttrs(3, "Styled", "", []string{"note"}, map[string]string{"title": `say "hi"`})
+    expected := "## Intro {#intro .lead .wide data-level=\"1\"}\n\n" +
+        "### Plain\n\n" +
+        "### Styled {.note title=\"say \\\"hi\\\"\"}\n\n"
+    compareOutput(t, "TestHeadingWithAttrs", expected, md.GetContent())
+
+    if err := md.HeadingWithAttrsE(2, "Bad", "has space", nil, nil); err == nil {
+        t.Error("TestHeadingWithAttrs: expected an error for an ID with a space")
+    }
+    if err := md.HeadingWithAttrsE(2, "Bad", "", []string{"two words"}, nil); err == nil {
+        t.Error("TestHeadingWithAttrs: expected an error for a class with a space")
+    }
+    compareOutput(t, "TestHeadingWithAttrs rejected", expected, md.GetContent())
+}
+
+func TestContentSince(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.Heading(1, "Log", "", "")
+    mark := md.Snapshot()
+    compareOutput(t, "TestContentSince empty", "", md.ContentSince(mark))
+    md.Paragraph("First event.")
+    md.Paragraph("Second event.")
+    compareOutput(t, "TestContentSince", "First event.\n\nSecond event.\n\n", md.ContentSince(mark))
+    compareOutput(t, "TestContentSince unknown mark", "", md.ContentSince(mark+10))
+    md.Prepend("Intro.")
+    compareOutput(t, "TestContentSince after Prepend", "First event.\n\nSecond event.\n\n", md.ContentSince(mark))
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.TOCPlaceholder()
+    md.Heading(2, "Usage", "", "")
+    mark = md.Snapshot()
+    md.Paragraph("Run it.")
+    md.RenderTOC()
+    compareOutput(t, "TestContentSince after RenderTOC", "Run it.\n\n", md.ContentSince(mark))
+    md.RestoreTo(mark)
+    compareOutput(t, "TestContentSince restore after RenderTOC", "- [Usage](#usage)\n\n## Usage\n\n", md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Paragraph("First  ")
+    mark = md.Snapshot()
+    md.Paragraph("Second")
+    md.Normalize()
+    compareOutput(t, "TestContentSince after Normalize", "", md.ContentSince(mark))
+    md.SetStrict(true)
+    md.RestoreTo(mark)
+    if md.Err() == nil {
+        t.Error("TestContentSince: expected Normalize to invalidate the mark")
+    }
+}
+
+func TestOrderedTree(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.OrderedTree([]markdown.ListItem{
+        {Text: "Scope", Children: []markdown.ListItem{
+            {Text: "Goals", Children: []markdown.ListItem{{Text: "Primary"}}},
+            {Text: ""},
+            {Text: "Non-goals"},
+        }},
+        {Text: "Design", Children: []markdown.ListItem{{Text: "Overview"}}},
+    })
+    md.OrderedTree(nil)
+    nbsp := strings.Repeat("&nbsp;", 4)
+    expected := "1\\. Scope\\\n" +
+        nbsp + "1.1 Goals\\\n" +
+        nbsp + nbsp + "1.1.1 Primary\\\n" +
+        nbsp + "1.2 Non-goals\\\n" +
+        "2\\. Design\\\n" +
+        nbsp + "2.1 Overview\n\n"
+    compareOutput(t, "TestOrderedTree", expected, md.GetContent())
+}
+
+func TestGistEmbed(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.SetHTMLOutput(true)
+    md.GistEmbed("https://gist.github.com/octocat/6cad326836d38bd3a7ae")
+    expected := "<script src=\"https://gist.github.com/octocat/6cad326836d38bd3a7ae.js\"></script>\n\n"
+    compareOutput(t, "TestGistEmbed embed", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.GistEmbed("https://gist.github.com/octocat/6cad326836d38bd3a7ae/")
+    expected = "[Gist octocat/6cad326836d38bd3a7ae](https://gist.github.com/octocat/6cad326836d38bd3a7ae)\n\n"
+    compareOutput(t, "TestGistEmbed link", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.SetStrict(true)
+    md.GistEmbed("https://github.com/octocat/hello-world")
+    if md.Err() == nil {
+        t.Error("TestGistEmbed: expected an error for a URL that is not a gist")
+    }
+}
+
+func TestDisplayWidth(t *testing.T) {
+    tests := []struct {
+        text  string
+        width int
+    }{
+        {"abc", 3},
+        {"日本語", 6},
+        {"café", 4},
+        {"cafe\u0301", 4}, // e followed by a combining acute accent
+        {"한국 ok", 7},
+        {"", 0},
+    }
+    for _, tt := range tests {
+        if got := markdown.DisplayWidth(tt.text); got != tt.width {
+            t.Errorf("TestDisplayWidth: DisplayWidth(%q) = %d, want %d", tt.text, got, tt.width)
+        }
+    }
+
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.SetAlignedTables(true)
+    md.Table([]string{"Name", "City"}, [][]string{{"José", "東京"}, {"Li", "Zürich"}}, []string{"left", "right"})
+    expected := "| Name |   City |\n" +
+        "| :--- | -----: |\n" +
+        "| José |   東京 |\n" +
+        "| Li   | Zürich |\n\n"
+    compareOutput(t, "TestDisplayWidth table", expected, md.GetContent())
+}
+
+func TestEscapeTableCell(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    compareOutput(t, "TestEscapeTableCell pipe", `a \| b`, md.EscapeTableCell("a | b"))
+    compareOutput(t, "TestEscapeTableCell escaped pipe", `a \| b`, md.EscapeTableCell(`a \| b`))
+    compareOutput(t, "TestEscapeTableCell newline", "line 1<br>line 2", md.EscapeTableCell("line 1\nline 2"))
+    compareOutput(t, "TestEscapeTableCell bold", "**bold**", md.EscapeTableCell("**bold**"))
+
+    md.Table([]string{"Expr", "Notes"}, [][]string{{"`a || b`", "**Short**\ncircuit"}}, []string{"left", "left"})
+    expected := "| Expr | Notes |\n|:---|:---|\n| `a \\|\\| b` | **Short**<br>circuit |\n\n"
+    compareOutput(t, "TestEscapeTableCell table", expected, md.GetContent())
+}
+
+func TestTableBuilderDefaultAlign(t *testing.T) {
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    md.NewTable("Name", "Qty").AddRow("Apple", "3").Render()
+    expected := "| Name | Qty |\n|---|---|\n| Apple | 3 |\n\n"
+    compareOutput(t, "TestTableBuilderDefaultAlign", expected, md.GetContent())
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.NewTable("Name", "Qty").SetAlign("left").AddRow("Apple", "3").Render()
+    expected = "| Name | Qty |\n|:---|---|\n| Apple | 3 |\n\n"
+    compareOutput(t, "TestTableBuilderDefaultAlign partial", expected, md.GetContent())
+}
+
+func TestFenceTracking(t *testing.T) {
+    doc := "````md\n```\n# *not a heading*\n~~~\n````\n\n~~~~\n# code\n~~~~~\n\n# *Heading*\n"
+    md := markdown.New(markdown.GitHubMarkdown, false)
+    compareOutput(t, "TestFenceTracking escape",
+        "````md\n```\n# *not a heading*\n~~~\n````\n\n~~~~\n# code\n~~~~~\n\n\\# \\*Heading\\*\n",
+        md.EscapeDocument(doc))
+
+    md = markdown.New(markdown.GitHubMarkdown, false)
+    md.Prepend(doc)
+    if stats := md.Stats(); stats.CodeBlocks != 2 || stats.Headings[0] != 1 {
+        t.Errorf("TestFenceTracking: Stats = %+v, expected 2 code blocks and 1 heading", stats)
+    }
+}
@@ -0,0 +1,87 @@
+package markdown
+
+import (
+    "strings"
+    "unicode"
+)
+
+// wideRanges lists the East Asian Wide and Fullwidth ranges of Unicode, whose
+// characters take two columns in a monospace font, as well as the emoji blocks
+// that terminals display with the same width.
+var wideRanges = [][2]rune{
+    {0x1100, 0x115F},   // Hangul Jamo initials
+    {0x231A, 0x231B},   // Watch, hourglass
+    {0x2329, 0x232A},   // Angle brackets
+    {0x23E9, 0x23EC},   // Media controls
+    {0x2E80, 0x303E},   // CJK radicals, symbols and punctuation
+    {0x3041, 0x33FF},   // Hiragana, Katakana, Bopomofo, CJK compatibility
+    {0x3400, 0x4DBF},   // CJK Unified Ideographs Extension A
+    {0x4E00, 0x9FFF},   // CJK Unified Ideographs
+    {0xA000, 0xA4CF},   // Yi
+    {0xA960, 0xA97F},   // Hangul Jamo Extended-A
+    {0xAC00, 0xD7A3},   // Hangul syllables
+    {0xF900, 0xFAFF},   // CJK compatibility ideographs
+    {0xFE10, 0xFE19},   // Vertical forms
+    {0xFE30, 0xFE6F},   // CJK compatibility forms, small form variants
+    {0xFF00, 0xFF60},   // Fullwidth forms
+    {0xFFE0, 0xFFE6},   // Fullwidth signs
+    {0x1F300, 0x1F64F}, // Pictographs and emoticons
+    {0x1F680, 0x1F6FF}, // Transport and map symbols
+    {0x1F900, 0x1F9FF}, // Supplemental symbols and pictographs
+    {0x20000, 0x2FFFD}, // CJK Unified Ideographs Extensions B to F
+    {0x30000, 0x3FFFD}, // CJK Unified Ideographs Extension G
+}
+
+// DisplayWidth returns the number of columns s takes in a monospace font, e.g.
+// to align table cells that mix scripts. Wide characters such as CJK
+// ideographs count as two columns; combining marks, zero-width characters, and
+// control characters count as none; all other characters count as one.
+//
+// Parameters:
+// - s: The text to measure
+//
+// Returns:
+// - int: The display width of s in columns
+func DisplayWidth(s string) int {
+    width := 0
+    for _, r := range s {
+        width += runeWidth(r)
+    }
+    return width
+}
+
+// runeWidth returns the number of columns r takes in a monospace font.
+func runeWidth(r rune) int {
+    switch {
+    case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Cc):
+        return 0
+    case r < wideRanges[0][0]:
+        return 1
+    }
+    for _, wide := range wideRanges {
+        if r < wide[0] {
+            break
+        }
+        if r <= wide[1] {
+            return 2
+        }
+    }
+    return 1
+}
+
+// padCell pads text with spaces to width columns according to the column
+// alignment: "right" pads on the left, "center" on both sides, and anything
+// else on the right.
+func padCell(text string, width int, align string) string {
+    gap := width - DisplayWidth(text)
+    if gap <= 0 {
+        return text
+    }
+    switch align {
+    case "right":
+        return strings.Repeat(" ", gap) + text
+    case "center":
+        return strings.Repeat(" ", gap/2) + text + strings.Repeat(" ", gap-gap/2)
+    }
+    return text + strings.Repeat(" ", gap)
+}